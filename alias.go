@@ -0,0 +1,255 @@
+package hm
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type alias struct {
+	params TypeVarSet
+	t      Type
+}
+
+// AliasEnv is a registry of type aliases (also known as type synonyms). For example, these:
+//		type Vec = (Float, Float, Float)
+//		type Pair a = (a, a)
+// are
+//		aliases := NewAliasEnv()
+//		aliases.Add("Vec", nil, NewRecordType("", Float, Float, Float))
+//		aliases.Add("Pair", TypeVarSet{'a'}, NewRecordType("", a, a))
+//
+// The types that use the aliases are AliasTypes: aliases.Alias("Pair", Float) is the type Pair Float.
+type AliasEnv struct {
+	aliases map[string]*alias
+}
+
+// NewAliasEnv creates a new empty AliasEnv
+func NewAliasEnv() *AliasEnv {
+	return &AliasEnv{aliases: make(map[string]*alias)}
+}
+
+// Add declares an alias with its parameters. The definition may only have the parameters as free TypeVariables.
+//
+// The definition may refer to aliases that are declared later, but the aliases may not refer to each other in a cycle.
+func (ae *AliasEnv) Add(name string, params TypeVarSet, t Type) error {
+	if _, ok := ae.aliases[name]; ok {
+		return errors.Errorf("Alias %v is already defined", name)
+	}
+	ftv := t.FreeTypeVar()
+	for _, tv := range ftv {
+		if !params.Contains(tv) {
+			return errors.Errorf("Type variable %v in the definition of alias %v is not a parameter", tv, name)
+		}
+	}
+
+	ae.aliases[name] = &alias{params: params, t: t}
+	if err := ae.check(name, t, []string{name}); err != nil {
+		delete(ae.aliases, name)
+		return err
+	}
+	return nil
+}
+
+// Alias returns the AliasType that applies the named alias to the arguments.
+// The alias does not have to be declared yet, but it has to be declared (with as many parameters as there are arguments) by the time it's expanded.
+func (ae *AliasEnv) Alias(name string, args ...Type) *AliasType {
+	return &AliasType{name: name, args: args, env: ae}
+}
+
+// lookup returns the declaration of the alias
+func (ae *AliasEnv) lookup(name string) (*alias, bool) {
+	a, ok := ae.aliases[name]
+	return a, ok
+}
+
+// check checks that the aliases used in t are applied to the right number of arguments, and that they don't lead back to the first alias in the path.
+func (ae *AliasEnv) check(name string, t Type, path []string) error {
+	if at, ok := t.(*AliasType); ok {
+		if at.name == path[0] {
+			return errors.Errorf("Alias %v is cyclic: %v", path[0], strings.Join(append(path, at.name), " → "))
+		}
+		if a, ok := ae.lookup(at.name); ok {
+			if len(a.params) != len(at.args) {
+				return errors.Errorf("Alias %v has %d parameters. It's applied to %d arguments in the definition of %v", at.name, len(a.params), len(at.args), name)
+			}
+			if err := ae.check(at.name, a.t, append(path, at.name)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, c := range t.Types() {
+		if err := ae.check(name, c, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AliasType is a type alias applied to arguments: Pair Float.
+// It is printed as the alias, and it is expanded to its definition lazily, when the definition is needed to unify it with another type.
+type AliasType struct {
+	name string
+	args Types
+	env  *AliasEnv
+}
+
+// Args returns the arguments of the alias
+func (t *AliasType) Args() Types { return t.args }
+
+// Expand returns the definition of the alias, with the parameters replaced by the arguments.
+// If the alias is not declared, or if it's applied to the wrong number of arguments, the AliasType itself is returned.
+func (t *AliasType) Expand() Type {
+	a, ok := t.env.lookup(t.name)
+	if !ok || len(a.params) != len(t.args) {
+		return t
+	}
+	if len(a.params) == 0 {
+		return cloneType(a.t)
+	}
+	sub := make(mSubs, len(a.params))
+	for i, p := range a.params {
+		sub[p] = t.args[i]
+	}
+	return cloneType(a.t).Apply(sub).(Type)
+}
+
+func (t *AliasType) Name() string { return t.name }
+
+func (t *AliasType) Apply(sub Subs) Substitutable {
+	if sub == nil || len(t.args) == 0 {
+		return t
+	}
+	args := make(Types, len(t.args))
+	for i, a := range t.args {
+		args[i] = a.Apply(sub).(Type)
+	}
+	return &AliasType{name: t.name, args: args, env: t.env}
+}
+
+func (t *AliasType) FreeTypeVar() TypeVarSet {
+	var tvs TypeVarSet
+	for _, a := range t.args {
+		tvs = a.FreeTypeVar().Union(tvs)
+	}
+	return tvs
+}
+
+func (t *AliasType) Normalize(k, v TypeVarSet) (Type, error) {
+	args := make(Types, len(t.args))
+	var err error
+	for i, a := range t.args {
+		if args[i], err = a.Normalize(k, v); err != nil {
+			return nil, err
+		}
+	}
+	return &AliasType{name: t.name, args: args, env: t.env}, nil
+}
+
+func (t *AliasType) Types() Types {
+	ts := BorrowTypes(len(t.args))
+	copy(ts, t.args)
+	return ts
+}
+
+// Eq returns true if the other type is the same alias applied to the same arguments.
+// Otherwise the alias is expanded, and compared with the other type (expanded, if it's an alias as well).
+func (t *AliasType) Eq(other Type) bool {
+	if ot, ok := other.(*AliasType); ok && ot.name == t.name && len(ot.args) == len(t.args) {
+		same := true
+		for i, a := range t.args {
+			if !a.Eq(ot.args[i]) {
+				same = false
+				break
+			}
+		}
+		if same {
+			return true
+		}
+	}
+
+	a, b := expandAlias(t), expandAlias(other)
+	if _, ok := a.(*AliasType); ok {
+		return false // undeclared aliases are only equal to themselves
+	}
+	return a.Eq(b)
+}
+
+func (t *AliasType) Format(s fmt.State, c rune) {
+	var buf bytes.Buffer
+	buf.WriteString(t.name)
+	for _, a := range t.args {
+		switch at := a.(type) {
+		case *ConstructedType:
+			if len(at.args) > 0 {
+				fmt.Fprintf(&buf, " (%v)", a)
+				continue
+			}
+		case *AliasType:
+			if len(at.args) > 0 {
+				fmt.Fprintf(&buf, " (%v)", a)
+				continue
+			}
+		case *FunctionType, *ForallType, *RecursiveType:
+			fmt.Fprintf(&buf, " (%v)", a)
+			continue
+		}
+		fmt.Fprintf(&buf, " %v", a)
+	}
+	s.Write(buf.Bytes())
+}
+
+func (t *AliasType) String() string { return fmt.Sprintf("%v", t) }
+
+// Clone implements Cloner
+func (t *AliasType) Clone() interface{} {
+	args := make(Types, len(t.args))
+	for i, a := range t.args {
+		args[i] = cloneType(a)
+	}
+	return &AliasType{name: t.name, args: args, env: t.env}
+}
+
+// expandAlias expands t until it's not an AliasType. The Eq methods of the other types expand the type they are compared with,
+// so that a type is equal to the aliases of it, whichever side the alias is on.
+func expandAlias(t Type) Type {
+	for {
+		at, ok := t.(*AliasType)
+		if !ok {
+			return t
+		}
+		if t = at.Expand(); t == Type(at) {
+			return t
+		}
+	}
+}
+
+// unaliasOccurring expands t if it's an AliasType whose arguments mention tv but whose expansion doesn't,
+// so that tv may be bound to it without failing the occurs check. Otherwise t is returned as it is.
+func unaliasOccurring(tv TypeVariable, t Type) Type {
+	if _, ok := t.(*AliasType); !ok || !occurs(tv, t) {
+		return t
+	}
+	if e := expandAlias(t); !occurs(tv, e) {
+		return e
+	}
+	return t
+}
+
+// expandAliases expands a and b if they are AliasTypes. It returns false if there is nothing to expand.
+func expandAliases(a, b Type) (Type, Type, bool) {
+	var expanded bool
+	if at, ok := a.(*AliasType); ok {
+		if a = expandAlias(at); a != Type(at) {
+			expanded = true
+		}
+	}
+	if bt, ok := b.(*AliasType); ok {
+		if b = expandAlias(bt); b != Type(bt) {
+			expanded = true
+		}
+	}
+	return a, b, expanded
+}
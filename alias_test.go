@@ -0,0 +1,204 @@
+package hm
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAliasEnv(t *testing.T) {
+	a := TypeVariable('a')
+	aliases := NewAliasEnv()
+	if err := aliases.Add("Vec", nil, NewRecordType("", Float, Float, Float)); err != nil {
+		t.Fatal(err)
+	}
+	if err := aliases.Add("Pair", TypeVarSet{'a'}, NewRecordType("", a, a)); err != nil {
+		t.Fatal(err)
+	}
+	if err := aliases.Add("Vec", nil, Float); err == nil {
+		t.Errorf("Expected an error when redefining Vec")
+	}
+	if err := aliases.Add("Bad", nil, NewRecordType("", a, a)); err == nil {
+		t.Errorf("Expected an error when the definition has a free TypeVariable that is not a parameter")
+	}
+	if err := aliases.Add("Bad", nil, aliases.Alias("Pair")); err == nil {
+		t.Errorf("Expected an error when Pair is applied to the wrong number of arguments")
+	}
+
+	// forward references are allowed, but cycles are not
+	if err := aliases.Add("A", nil, NewConstructedType("List", aliases.Alias("B"))); err != nil {
+		t.Errorf("Expected forward references to be allowed. Got %v", err)
+	}
+	if err := aliases.Add("B", nil, aliases.Alias("A")); err == nil {
+		t.Errorf("Expected an error when the aliases are cyclic")
+	}
+	if err := aliases.Add("C", nil, NewFnType(Float, aliases.Alias("C"))); err == nil {
+		t.Errorf("Expected an error when the alias refers to itself")
+	}
+	if err := aliases.Add("B", nil, Float); err != nil {
+		t.Errorf("Expected B to be declared after a failed declaration. Got %v", err)
+	}
+}
+
+func TestAliasType(t *testing.T) {
+	a := TypeVariable('a')
+	aliases := NewAliasEnv()
+	aliases.Add("Vec", nil, NewRecordType("", Float, Float, Float))
+	aliases.Add("Pair", TypeVarSet{'a'}, NewRecordType("", a, a))
+	aliases.Add("Vecs", nil, aliases.Alias("Pair", aliases.Alias("Vec")))
+	aliases.Add("Id", TypeVarSet{'a'}, a)
+
+	vec := aliases.Alias("Vec")
+	pairFloat := aliases.Alias("Pair", Float)
+
+	aliasFormats := []struct {
+		t       Type
+		correct string
+	}{
+		{vec, "Vec"},
+		{pairFloat, "Pair Float"},
+		{aliases.Alias("Pair", aliases.Alias("Pair", a)), "Pair (Pair a)"},
+		{aliases.Alias("Pair", NewFnType(a, a)), "Pair (a → a)"},
+		{NewFnType(vec, Float), "Vec → Float"},
+		{pairFloat.Expand(), "(Float, Float)"},
+	}
+	for _, af := range aliasFormats {
+		if got := fmt.Sprintf("%v", af.t); got != af.correct {
+			t.Errorf("Expected %v. Got %v", af.correct, got)
+		}
+	}
+
+	if !pairFloat.Eq(NewRecordType("", Float, Float)) {
+		t.Errorf("Expected Pair Float to be equal to its definition")
+	}
+	if !aliases.Alias("Vecs").Eq(NewRecordType("", vec, NewRecordType("", Float, Float, Float))) {
+		t.Errorf("Expected Vecs to be equal to its definition")
+	}
+	if pairFloat.Eq(NewRecordType("", Float, Bool)) || pairFloat.Eq(vec) {
+		t.Errorf("Expected Pair Float to not be equal to other types")
+	}
+
+	// the alias may be on either side
+	rec := NewRecordType("", Float, Float, Float)
+	if !rec.Eq(vec) || !NewRecordType("", Float, Float).Eq(pairFloat) || !proton.Eq(aliases.Alias("Id", proton)) || !a.Eq(aliases.Alias("Id", a)) {
+		t.Errorf("Expected the definitions to be equal to their aliases")
+	}
+	if !NewFnType(rec, proton).Eq(NewFnType(vec, proton)) || !NewFnType(vec, proton).Eq(NewFnType(rec, proton)) {
+		t.Errorf("Expected Vec → proton to be equal to (Float, Float, Float) → proton")
+	}
+	if rec.Eq(pairFloat) || NewFnType(rec, proton).Eq(NewFnType(pairFloat, proton)) {
+		t.Errorf("Expected (Float, Float, Float) to not be equal to Pair Float")
+	}
+	undeclared := aliases.Alias("Undeclared")
+	if !undeclared.Eq(aliases.Alias("Undeclared")) || undeclared.Eq(Float) || undeclared.Expand() != Type(undeclared) {
+		t.Errorf("Expected an undeclared alias to only be equal to itself")
+	}
+
+	// substitutions are applied to the arguments, and not to the definition
+	pair := aliases.Alias("Pair", TypeVariable('b'))
+	if got := pair.Apply(mSubs{'b': Float, 'a': Bool}).(Type); !got.Eq(pairFloat) || !pair.Expand().Eq(NewRecordType("", TypeVariable('b'), TypeVariable('b'))) {
+		t.Errorf("Expected Pair Float and an unchanged Pair b. Got %v and %v", got, pair)
+	}
+	if ftv := pair.FreeTypeVar(); !ftv.Equals(TypeVarSet{'b'}) {
+		t.Errorf("Expected the free TypeVariables of Pair b to be [b]. Got %v", ftv)
+	}
+}
+
+func TestUnify_Aliases(t *testing.T) {
+	a, b, c := TypeVariable('a'), TypeVariable('b'), TypeVariable('c')
+	aliases := NewAliasEnv()
+	aliases.Add("Vec", nil, NewRecordType("", Float, Float, Float))
+	aliases.Add("Pair", TypeVarSet{'a'}, NewRecordType("", a, a))
+
+	sub, err := Unify(aliases.Alias("Vec"), NewRecordType("", a, b, c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tv := range []TypeVariable{a, b, c} {
+		if got, ok := sub.Get(tv); !ok || got != Float {
+			t.Errorf("Expected %v to be Float. Got %v", tv, got)
+		}
+	}
+
+	if sub, err = Unify(aliases.Alias("Pair", a), aliases.Alias("Pair", Float)); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := sub.Get(a); !ok || got != Float {
+		t.Errorf("Expected a to be Float. Got %v", got)
+	}
+
+	if _, err = Unify(aliases.Alias("Pair", a), aliases.Alias("Vec")); err == nil {
+		t.Errorf("Expected Pair a to not unify with Vec")
+	}
+	if _, err = Unify(aliases.Alias("Undeclared"), Float); err == nil {
+		t.Errorf("Expected an undeclared alias to not unify with Float")
+	}
+
+	// the occurs check is on the expansion of the alias
+	aliases.Add("Const", TypeVarSet{'a'}, proton)
+	if sub, err = Unify(a, aliases.Alias("Const", a)); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := sub.Get(a); !ok || got != proton {
+		t.Errorf("Expected a to be proton. Got %v", got)
+	}
+	if _, err = Unify(a, aliases.Alias("Pair", a)); err == nil {
+		t.Errorf("Expected a to not unify with Pair a")
+	}
+	uf := newUFSolver()
+	uf.solve(Constraints{{a: a, b: aliases.Alias("Const", a)}})
+	if got, ok := uf.sub.Get(a); uf.err != nil || !ok || got != proton {
+		t.Errorf("Expected a to be proton with the union-find solver. Got %v, %v", got, uf.err)
+	}
+}
+
+func TestInfer_Aliases(t *testing.T) {
+	a := TypeVariable('a')
+	aliases := NewAliasEnv()
+	aliases.Add("Vec", nil, NewRecordType("", Float, Float, Float))
+	aliases.Add("Pair", TypeVarSet{'a'}, NewRecordType("", a, a))
+	vec, pair := aliases.Alias("Vec"), aliases.Alias("Pair", a)
+
+	env := SimpleEnv{
+		"norm":   NewScheme(nil, NewFnType(vec, Float)),
+		"origin": NewScheme(nil, vec),
+		"swap":   NewScheme(TypeVarSet{'a'}, NewFnType(pair, pair)),
+		"dup":    NewScheme(TypeVarSet{'a'}, NewFnType(a, pair)),
+	}
+
+	aliasTests := []struct {
+		name string
+		expr Expression
+
+		correct string
+		err     bool
+	}{
+		{"norm", lit("norm"), "∀[]: Vec → Float", false},
+		{"norm origin", app{lit("norm"), lit("origin")}, "∀[]: Float", false},
+		{"norm (1, 2, 3)", app{lit("norm"), tuple{lit("1"), lit("2"), lit("3")}}, "∀[]: Float", false},
+		{"norm (1, 2)", app{lit("norm"), tuple{lit("1"), lit("2")}}, "", true},
+		{"swap", lit("swap"), "∀[a]: Pair a → Pair a", false},
+		{"swap (1, 2)", app{lit("swap"), tuple{lit("1"), lit("2")}}, "∀[]: Pair Float", false},
+		{"swap (1, true)", app{lit("swap"), tuple{lit("1"), lit("true")}}, "", true},
+		{"λx. swap (dup x)", λ{"x", app{lit("swap"), app{lit("dup"), lit("x")}}}, "∀[a]: a → Pair a", false},
+		{"proj 0 origin", proj{rec: lit("origin"), index: 0}, "∀[]: Float", false},
+	}
+
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		for _, ats := range aliasTests {
+			sch, err := Infer(env, ats.expr, opts...)
+			if ats.err {
+				if err == nil {
+					t.Errorf("Test %q: Expected an error. Got %v", ats.name, sch)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("Test %q: %v", ats.name, err)
+				continue
+			}
+			if got := fmt.Sprintf("%v", sch); got != ats.correct {
+				t.Errorf("Test %q: Expected %v. Got %v", ats.name, ats.correct, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package hm
+
+import "github.com/pkg/errors"
+
+// Check checks that the expression is of the expected type.
+//
+// Unlike Infer, the expected type is pushed inwards through Lambda, Let and Apply:
+// the parameters of a Lambda get their types from the expected function type, the body of a Let is checked against the expected type,
+// and the argument of an Apply is checked against the parameter type of the function.
+// So a type error is reported at the innermost expression that doesn't have the type it's expected to have,
+// rather than where the inferred types happen to clash.
+//
+// The expected type is a signature: its TypeVariables are rigid, as if they were quantified by an annotation.
+func Check(env Env, expr Expression, expected Type, opts ...InferOpt) error {
+	if expr == nil {
+		return errors.Errorf("Cannot check a nil expression")
+	}
+	if expected == nil {
+		return errors.Errorf("Cannot check %v against a nil type", expr)
+	}
+
+	if env == nil {
+		env = make(SimpleEnv)
+	}
+
+	infer := newInferer(env)
+	for _, opt := range opts {
+		opt(infer)
+	}
+
+	if err := infer.kindCheckDataTypes(); err != nil {
+		return err
+	}
+	if err := infer.kindCheck(expected); err != nil {
+		return err
+	}
+	infer.noteForalls(expected)
+	expected, _, sks := infer.skolemize(closeScheme(NewScheme(nil, expected)))
+	_, ps, _, err := infer.top(expr, expected)
+	if err != nil {
+		return err
+	}
+
+	// the signature has no predicates, so the predicates on its TypeVariables are missing from it
+	for _, p := range ps {
+		for _, pt := range p.Types {
+			if _, ok := mentions(pt, sks); ok {
+				return NoInstanceError{p}
+			}
+		}
+	}
+	if len(ps) > 0 {
+		return AmbiguityError{ps, expected}
+	}
+	return nil
+}
+
+// check generates the constraints that make the expression be of the expected type.
+// Afterwards, infer.t is the expected type.
+func (infer *inferer) check(expr Expression, expected Type) (err error) {
+	// recorded is true if the type of the expression has been recorded already (by consGen, or by the check against the skolemized type)
+	var recorded bool
+	defer func() {
+		if err == nil && !recorded {
+			infer.typed(expr, expected)
+		}
+		err = withPos(expr, err)
+	}()
+
+	if ft, ok := expandAlias(expected).(*ForallType); ok {
+		recorded = true
+		if err = infer.checkForall(expr, ft); err == nil && infer.typing != nil {
+			// the expression is the last node to be recorded. Its type is the ForallType rather than the skolemized type
+			infer.typing.Nodes[len(infer.typing.Nodes)-1].Type = expected
+		}
+		return err
+	}
+
+	switch et := expr.(type) {
+	case Typer, Inferer:
+		// literals and expressions that know their own types are inferred
+	case Lambda:
+		return infer.checkLambda(et, expected)
+	case LetRec:
+		// the body of a letrec is inferred
+	case Let:
+		var sc *Scheme
+		if sc, err = infer.letDef(et); err != nil {
+			return err
+		}
+		env := infer.env // backup
+		infer.env = infer.env.Clone()
+		infer.env.Remove(et.Name())
+		infer.env.Add(et.Name(), sc)
+
+		if err = infer.check(et.Body(), expected); err != nil {
+			return errors.Wrapf(err, "Unable to check body of let %v. Body: %v", et, et.Body())
+		}
+		infer.env = env // restore backup
+		return nil
+	case Apply:
+		return infer.checkApply(et, expected)
+	}
+	recorded = true
+	return infer.subsume(expr, expected)
+}
+
+// subsume infers the type of the expression, and constrains it to be the expected type (or a subtype of it, WithSubtyping).
+func (infer *inferer) subsume(expr Expression, expected Type) error {
+	if err := infer.consGen(expr); err != nil {
+		return err
+	}
+	origin := &Origin{Expr: expr, Reason: ReasonCheck, Parent: infer.origin}
+	if infer.lattice != nil {
+		infer.subtypes = append(infer.subtypes, SubtypeConstraint{infer.t, expected, origin})
+	} else {
+		infer.cs = append(infer.cs, Constraint{infer.t, expected, origin})
+	}
+	infer.t = expected
+	return nil
+}
+
+func (infer *inferer) checkLambda(et Lambda, expected Type) (err error) {
+	ft, ok := infer.resolve(expected).(*FunctionType)
+	if !ok {
+		return infer.subsume(et, expected)
+	}
+
+	param := ft.Arg()
+	if tl, ok := et.(TypedLambda); ok && tl.ParamType() != nil {
+		pt := tl.ParamType()
+		infer.noteForalls(pt)
+		if err = infer.kindCheck(pt); err != nil {
+			return err
+		}
+		annotated, _ := instantiate(infer, &Scheme{tvs: pt.FreeTypeVar(), t: pt})
+		origin := &Origin{Expr: et, Reason: ReasonCheck, Parent: infer.origin}
+		infer.cs = append(infer.cs, Constraint{annotated, param, origin})
+	}
+
+	env := infer.env // backup
+	infer.env = infer.env.Clone()
+	infer.env.Remove(et.Name())
+	infer.env.Add(et.Name(), &Scheme{t: param})
+
+	// the body may only perform the effects of the expected function type
+	eff := infer.eff
+	if eff != nil {
+		infer.eff = effectRow(ft.eff)
+	}
+	if err = infer.check(et.Body(), ft.Ret(false)); err != nil {
+		return errors.Wrapf(err, "Unable to check body of %v. Body: %v", et, et.Body())
+	}
+
+	infer.t = expected
+	infer.env = env // restore backup
+	infer.eff = eff
+	return nil
+}
+
+func (infer *inferer) checkApply(et Apply, expected Type) (err error) {
+	origin := &Origin{Expr: et, Reason: ReasonApply, Parent: infer.origin}
+	infer.origin = origin
+	defer func() { infer.origin = origin.Parent }()
+
+	if err = infer.consGen(et.Fn()); err != nil {
+		return errors.Wrapf(err, "Unable to infer Fn of Apply: %v. Fn: %v", et, et.Fn())
+	}
+	fnType := infer.t
+
+	ft, ok := infer.resolve(fnType).(*FunctionType)
+	if !ok {
+		// the parameter type is not known, so the argument is inferred
+		if err = infer.consGen(et.Body()); err != nil {
+			return errors.Wrapf(err, "Unable to infer body of Apply: %v. Body: %v", et, et.Body())
+		}
+		infer.cs = append(infer.cs, Constraint{fnType, infer.fnType(infer.t, expected), origin})
+		infer.t = expected
+		return nil
+	}
+
+	if err = infer.check(et.Body(), ft.Arg()); err != nil {
+		return errors.Wrapf(err, "Unable to check body of Apply: %v. Body: %v", et, et.Body())
+	}
+	infer.perform(ft, origin)
+	infer.cs = append(infer.cs, Constraint{infer.instantiateForall(ft.Ret(false)), expected, &Origin{Expr: et, Reason: ReasonCheck, Parent: origin.Parent}})
+	infer.t = expected
+	return nil
+}
+
+// resolve returns what the constraints so far say a TypeVariable is, with aliases expanded. Other types are returned as they are.
+func (infer *inferer) resolve(t Type) Type {
+	if _, ok := t.(TypeVariable); !ok {
+		return expandAlias(t)
+	}
+	sub, err := infer.solution()
+	if err != nil || sub == nil {
+		// the error is reported when the constraints are solved at the end
+		return t
+	}
+	return expandAlias(cloneType(t).Apply(sub).(Type))
+}
@@ -0,0 +1,87 @@
+package hm
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestCheck(t *testing.T) {
+	a := TypeVariable('a')
+	env := SimpleEnv{
+		"+":    NewQualifiedScheme(TypeVarSet{'a'}, Predicates{NewPredicate("Num", a)}, NewFnType(a, a, a)),
+		"plus": NewScheme(nil, NewFnType(Float, Float, Float)),
+		"id":   NewScheme(TypeVarSet{'a'}, NewFnType(a, a)),
+	}
+
+	checkTests := []struct {
+		name     string
+		expr     Expression
+		expected Type
+
+		err ErrorCode
+	}{
+		{"1 : Float", lit("1"), Float, 0},
+		{"true : Float", lit("true"), Float, CodeUnification},
+		{"λx. x : Float → Float", λ{"x", lit("x")}, NewFnType(Float, Float), 0},
+		{"λx. x : a → a", λ{"x", lit("x")}, NewFnType(a, a), 0},
+		{"λx. plus x 1 : a → a", λ{"x", app{app{lit("plus"), lit("x")}, lit("1")}}, NewFnType(a, a), CodeRigidTypeVariable},
+		{"λx. plus x 1 : Float → Bool", λ{"x", app{app{lit("plus"), lit("x")}, lit("1")}}, NewFnType(Float, Bool), CodeUnification},
+		{"λx. λy. x : a → Float → a", λ{"x", λ{"y", lit("x")}}, NewFnType(a, Float, a), 0},
+		{"let f = λx. x in f 1 : Float", let{"f", λ{"x", lit("x")}, app{lit("f"), lit("1")}}, Float, 0},
+		{"let f = λx. x in f 1 : Bool", let{"f", λ{"x", lit("x")}, app{lit("f"), lit("1")}}, Bool, CodeUnification},
+		{"id (λx. x) : Float → Float", app{lit("id"), λ{"x", lit("x")}}, NewFnType(Float, Float), 0},
+		{"λ(x : Bool). x : Float → Float", typedλ{"x", Bool, lit("x")}, NewFnType(Float, Float), CodeUnification},
+		{"λ(x : a). x : Float → Float", typedλ{"x", a, lit("x")}, NewFnType(Float, Float), 0},
+		{"λx. + x x : Float → Float", λ{"x", app{app{lit("+"), lit("x")}, lit("x")}}, NewFnType(Float, Float), 0},
+		{"λx. + x x : a → a", λ{"x", app{app{lit("+"), lit("x")}, lit("x")}}, NewFnType(a, a), CodeNoInstance},
+		{"λx. let y = + x x in y : a → a", λ{"x", let{"y", app{app{lit("+"), lit("x")}, lit("x")}, lit("y")}}, NewFnType(a, a), CodeNoInstance},
+	}
+
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		opts = append(opts, WithClasses(numClasses()))
+		for _, cts := range checkTests {
+			err := Check(env, cts.expr, cts.expected, opts...)
+			if cts.err == 0 {
+				if err != nil {
+					t.Errorf("Test %q: %+v", cts.name, err)
+				}
+				continue
+			}
+			var te TypeError
+			if !errors.As(err, &te) || te.Code() != cts.err {
+				t.Errorf("Test %q: Expected a %v error. Got %v", cts.name, cts.err, err)
+			}
+		}
+	}
+}
+
+func TestCheck_Position(t *testing.T) {
+	env := SimpleEnv{"plus": NewScheme(nil, NewFnType(Float, Float, Float))}
+
+	// λx. plus x true
+	wrong := posLit{lit("true"), newSpan(1, 15, 4)}
+	body := posApp{app{app{lit("plus"), lit("x")}, wrong}, newSpan(1, 6, 13)}
+	expr := λ{"x", body}
+
+	// Infer reports the application, as that is where the types clash
+	_, err := Infer(env, expr)
+	var pe *PositionError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Expected a *PositionError. Got %v instead", err)
+	}
+	if start, _ := pe.Span(); start != body.start {
+		t.Errorf("Expected Infer to report the application at %v. Got %v", body.start, start)
+	}
+
+	// Check reports the argument, as that is what doesn't have the expected type
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		err = Check(env, expr, NewFnType(Float, Float), opts...)
+		if !errors.As(err, &pe) {
+			t.Fatalf("Expected a *PositionError. Got %v instead", err)
+		}
+		if start, _ := pe.Span(); start != wrong.start {
+			t.Errorf("Expected Check to report the argument at %v. Got %v: %v", wrong.start, start, err)
+		}
+	}
+}
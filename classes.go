@@ -0,0 +1,233 @@
+package hm
+
+import "github.com/pkg/errors"
+
+// Instance is an instance declaration of a type class. The instance holds if all the predicates in its context hold.
+// For example, this instance:
+//		Eq a ⇒ Eq (List a)
+// has Eq a as its context, and Eq (List a) as its head.
+type Instance struct {
+	Context Predicates
+	Head    Predicate
+}
+
+type class struct {
+	supers    []string
+	instances []Instance
+}
+
+// ClassEnv is an environment of type classes, their superclasses and their instances.
+// It is used to resolve the predicates that arise during inference.
+type ClassEnv struct {
+	classes map[string]*class
+}
+
+// NewClassEnv creates a new empty ClassEnv
+func NewClassEnv() *ClassEnv {
+	return &ClassEnv{classes: make(map[string]*class)}
+}
+
+// AddClass declares a class with its superclasses. The superclasses have to be declared first.
+func (ce *ClassEnv) AddClass(name string, supers ...string) error {
+	if _, ok := ce.classes[name]; ok {
+		return errors.Errorf("Class %v is already defined", name)
+	}
+	for _, s := range supers {
+		if _, ok := ce.classes[s]; !ok {
+			return errors.Errorf("Superclass %v of %v is not defined", s, name)
+		}
+	}
+	ce.classes[name] = &class{supers: supers}
+	return nil
+}
+
+// AddInstance declares an instance. The class has to be declared first, and the instance may not overlap with any existing instance.
+func (ce *ClassEnv) AddInstance(context Predicates, head Predicate) error {
+	c, ok := ce.classes[head.Class]
+	if !ok {
+		return errors.Errorf("Class %v is not defined", head.Class)
+	}
+	for _, p := range context {
+		if _, ok := ce.classes[p.Class]; !ok {
+			return errors.Errorf("Class %v in the context of %v is not defined", p.Class, head)
+		}
+	}
+
+	for _, inst := range c.instances {
+		if overlaps(inst.Head, head) {
+			return errors.Errorf("Instance %v overlaps with instance %v", head, inst.Head)
+		}
+	}
+	c.instances = append(c.instances, Instance{Context: context, Head: head})
+	return nil
+}
+
+// Supers returns the superclasses of a class.
+func (ce *ClassEnv) Supers(name string) []string {
+	if c, ok := ce.classes[name]; ok {
+		return c.supers
+	}
+	return nil
+}
+
+// Instances returns the instances of a class.
+func (ce *ClassEnv) Instances(name string) []Instance {
+	if c, ok := ce.classes[name]; ok {
+		return c.instances
+	}
+	return nil
+}
+
+// bySuper returns the predicate and all the predicates that it implies by way of superclasses.
+func (ce *ClassEnv) bySuper(p Predicate) Predicates {
+	retVal := Predicates{p}
+	for _, s := range ce.Supers(p.Class) {
+		retVal = append(retVal, ce.bySuper(Predicate{Class: s, Types: p.Types})...)
+	}
+	return retVal
+}
+
+// byInst finds the instance whose head matches the predicate, and returns its context.
+func (ce *ClassEnv) byInst(p Predicate) (Predicates, bool) {
+	for _, inst := range ce.Instances(p.Class) {
+		if len(inst.Head.Types) != len(p.Types) {
+			continue
+		}
+
+		sub := make(mSubs)
+		matched := true
+		for i, t := range inst.Head.Types {
+			if !match(t, p.Types[i], sub) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return inst.Context.Apply(sub).(Predicates), true
+		}
+	}
+	return nil, false
+}
+
+// Entails returns true if the predicate p holds whenever all the predicates in ps hold.
+func (ce *ClassEnv) Entails(ps Predicates, p Predicate) bool {
+	for _, q := range ps {
+		if ce.bySuper(q).Contains(p) {
+			return true
+		}
+	}
+
+	ctx, ok := ce.byInst(p)
+	if !ok {
+		return false
+	}
+	for _, q := range ctx {
+		if !ce.Entails(ps, q) {
+			return false
+		}
+	}
+	return true
+}
+
+// Reduce simplifies a list of predicates. Predicates are resolved with the instances until only predicates on type variables remain,
+// and then the predicates that are entailed by the other predicates are removed.
+//
+// A NoInstanceError is returned if a predicate on concrete types cannot be resolved.
+func (ce *ClassEnv) Reduce(ps Predicates) (Predicates, error) {
+	var hnf Predicates
+	for _, p := range ps {
+		qs, err := ce.toHNF(p)
+		if err != nil {
+			return nil, err
+		}
+		hnf = append(hnf, qs...)
+	}
+	return ce.simplify(hnf), nil
+}
+
+// toHNF resolves the predicate with the instances, until the predicates are in head normal form - that is to say, they are on type variables.
+func (ce *ClassEnv) toHNF(p Predicate) (Predicates, error) {
+	if ctx, ok := ce.byInst(p); ok {
+		var retVal Predicates
+		for _, q := range ctx {
+			qs, err := ce.toHNF(q)
+			if err != nil {
+				return nil, err
+			}
+			retVal = append(retVal, qs...)
+		}
+		return retVal, nil
+	}
+
+	ftv := p.FreeTypeVar()
+	defer ReturnTypeVarSet(ftv)
+	if len(ftv) == 0 {
+		return nil, NoInstanceError{p}
+	}
+	return Predicates{p}, nil
+}
+
+// simplify removes the predicates that are entailed by the rest.
+func (ce *ClassEnv) simplify(ps Predicates) Predicates {
+	var retVal Predicates
+	for i, p := range ps {
+		rest := make(Predicates, 0, len(ps))
+		rest = append(rest, retVal...)
+		rest = append(rest, ps[i+1:]...)
+		if !ce.Entails(rest, p) {
+			retVal = append(retVal, p)
+		}
+	}
+	return retVal
+}
+
+// overlaps returns true if there is a predicate that matches both instance heads.
+func overlaps(a, b Predicate) bool {
+	if len(a.Types) != len(b.Types) {
+		return false
+	}
+	as := make(Types, len(a.Types))
+	bs := make(Types, len(b.Types))
+	for i := range a.Types {
+		// unifyMany mutates the types
+		as[i] = cloneType(a.Types[i])
+		bs[i] = cloneType(b.Types[i])
+	}
+	_, err := unifyMany(newTypesFresher(append(append(Types(nil), as...), bs...)...), as, bs)
+	return err == nil
+}
+
+// match finds the substitution that makes the pattern equal to t. Only the type variables in the pattern are bound.
+func match(pattern, t Type, sub mSubs) bool {
+	if tv, ok := pattern.(TypeVariable); ok {
+		if bound, ok := sub[tv]; ok {
+			return bound.Eq(t)
+		}
+		sub[tv] = t
+		return true
+	}
+
+	if _, ok := t.(TypeVariable); ok {
+		return false
+	}
+	if !sameConstructor(pattern, t) {
+		return false
+	}
+
+	pts := pattern.Types()
+	ts := t.Types()
+	defer ReturnTypes(pts)
+	defer ReturnTypes(ts)
+	if len(pts) != len(ts) {
+		return false
+	}
+	if len(pts) == 0 {
+		return pattern.Eq(t)
+	}
+	for i, pt := range pts {
+		if !match(pt, ts[i], sub) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,214 @@
+package hm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// numClasses creates the following classes:
+//		class Eq a
+//		class Eq a ⇒ Ord a
+//		class Num a
+//		class Show a
+//		class Read a
+// with the instances Eq Float, Ord Float, Num Float, Eq Bool, Show Float, Read Float and Eq a ⇒ Eq (List a)
+func numClasses() *ClassEnv {
+	ce := NewClassEnv()
+	mustNil := func(err error) {
+		if err != nil {
+			panic(err)
+		}
+	}
+	mustNil(ce.AddClass("Eq"))
+	mustNil(ce.AddClass("Ord", "Eq"))
+	mustNil(ce.AddClass("Num"))
+	mustNil(ce.AddClass("Show"))
+	mustNil(ce.AddClass("Read"))
+
+	mustNil(ce.AddInstance(nil, NewPredicate("Eq", Float)))
+	mustNil(ce.AddInstance(nil, NewPredicate("Eq", Bool)))
+	mustNil(ce.AddInstance(nil, NewPredicate("Ord", Float)))
+	mustNil(ce.AddInstance(nil, NewPredicate("Num", Float)))
+	mustNil(ce.AddInstance(nil, NewPredicate("Show", Float)))
+	mustNil(ce.AddInstance(nil, NewPredicate("Read", Float)))
+	mustNil(ce.AddInstance(Predicates{NewPredicate("Eq", TypeVariable('a'))}, NewPredicate("Eq", list{TypeVariable('a')})))
+	return ce
+}
+
+func TestClassEnv(t *testing.T) {
+	ce := numClasses()
+
+	if err := ce.AddClass("Eq"); err == nil {
+		t.Error("Expected an error when redefining a class")
+	}
+	if err := ce.AddClass("Integral", "Real"); err == nil {
+		t.Error("Expected an error when the superclass is not defined")
+	}
+	if err := ce.AddInstance(nil, NewPredicate("Monoid", Float)); err == nil {
+		t.Error("Expected an error when the class is not defined")
+	}
+	if err := ce.AddInstance(nil, NewPredicate("Eq", list{Float})); err == nil {
+		t.Error("Expected an error when the instance overlaps Eq (List a)")
+	}
+	if err := ce.AddInstance(nil, NewPredicate("Eq", list{TypeVariable('a')})); err == nil {
+		t.Error("Expected an error when the instance overlaps Eq (List a)")
+	}
+
+	if supers := ce.Supers("Ord"); len(supers) != 1 || supers[0] != "Eq" {
+		t.Errorf("Expected Ord to have Eq as its superclass. Got %v", supers)
+	}
+	if insts := ce.Instances("Eq"); len(insts) != 3 {
+		t.Errorf("Expected 3 instances of Eq. Got %v", insts)
+	}
+}
+
+func TestClassEnv_Entails(t *testing.T) {
+	ce := numClasses()
+	a := TypeVariable('a')
+
+	entailTests := []struct {
+		name    string
+		ps      Predicates
+		p       Predicate
+		correct bool
+	}{
+		{"instance", nil, NewPredicate("Num", Float), true},
+		{"no instance", nil, NewPredicate("Num", Bool), false},
+		{"assumption", Predicates{NewPredicate("Num", a)}, NewPredicate("Num", a), true},
+		{"superclass", Predicates{NewPredicate("Ord", a)}, NewPredicate("Eq", a), true},
+		{"not a superclass", Predicates{NewPredicate("Eq", a)}, NewPredicate("Ord", a), false},
+		{"instance context", nil, NewPredicate("Eq", list{list{Float}}), true},
+		{"instance context from assumption", Predicates{NewPredicate("Ord", a)}, NewPredicate("Eq", list{a}), true},
+		{"instance context fails", nil, NewPredicate("Eq", list{a}), false},
+	}
+
+	for _, ets := range entailTests {
+		if got := ce.Entails(ets.ps, ets.p); got != ets.correct {
+			t.Errorf("Test %q: Expected %v ⊩ %v to be %t", ets.name, ets.ps, ets.p, ets.correct)
+		}
+	}
+}
+
+func TestClassEnv_Reduce(t *testing.T) {
+	ce := numClasses()
+	a := TypeVariable('a')
+	b := TypeVariable('b')
+
+	reduceTests := []struct {
+		name    string
+		ps      Predicates
+		correct Predicates
+		err     ErrorCode
+	}{
+		{"resolved", Predicates{NewPredicate("Num", Float)}, nil, 0},
+		{"head normal form", Predicates{NewPredicate("Num", a)}, Predicates{NewPredicate("Num", a)}, 0},
+		{"instance context", Predicates{NewPredicate("Eq", list{a})}, Predicates{NewPredicate("Eq", a)}, 0},
+		{"superclass", Predicates{NewPredicate("Eq", a), NewPredicate("Ord", a)}, Predicates{NewPredicate("Ord", a)}, 0},
+		{"duplicates", Predicates{NewPredicate("Num", a), NewPredicate("Num", b), NewPredicate("Num", a)}, Predicates{NewPredicate("Num", b), NewPredicate("Num", a)}, 0},
+		{"no instance", Predicates{NewPredicate("Num", Bool)}, nil, CodeNoInstance},
+		{"no instance in context", Predicates{NewPredicate("Eq", list{proton})}, nil, CodeNoInstance},
+	}
+
+	for _, rts := range reduceTests {
+		ps, err := ce.Reduce(rts.ps)
+		if rts.err != 0 {
+			var te TypeError
+			if !errors.As(err, &te) || te.Code() != rts.err {
+				t.Errorf("Test %q: Expected a %v error. Got %v", rts.name, rts.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %q: %v", rts.name, err)
+			continue
+		}
+		if len(ps) != len(rts.correct) {
+			t.Errorf("Test %q: Expected %v. Got %v", rts.name, rts.correct, ps)
+			continue
+		}
+		for _, p := range rts.correct {
+			if !ps.Contains(p) {
+				t.Errorf("Test %q: Expected %v. Got %v", rts.name, rts.correct, ps)
+				break
+			}
+		}
+	}
+}
+
+func TestInfer_Classes(t *testing.T) {
+	a := TypeVariable('a')
+	env := SimpleEnv{
+		"+":    NewQualifiedScheme(TypeVarSet{'a'}, Predicates{NewPredicate("Num", a)}, NewFnType(a, a, a)),
+		"==":   NewQualifiedScheme(TypeVarSet{'a'}, Predicates{NewPredicate("Eq", a)}, NewFnType(a, a, Bool)),
+		"<":    NewQualifiedScheme(TypeVarSet{'a'}, Predicates{NewPredicate("Ord", a)}, NewFnType(a, a, Bool)),
+		"show": NewQualifiedScheme(TypeVarSet{'a'}, Predicates{NewPredicate("Show", a)}, NewFnType(a, proton)),
+		"read": NewQualifiedScheme(TypeVarSet{'a'}, Predicates{NewPredicate("Read", a)}, NewFnType(proton, a)),
+		"nil":  NewScheme(TypeVarSet{'a'}, list{a}),
+		"id":   NewScheme(TypeVarSet{'a'}, NewFnType(a, a)),
+	}
+
+	inferClassTests := []struct {
+		name string
+		expr Expression
+
+		correct string
+		err     ErrorCode
+	}{
+		{"+ 1 1", app{app{lit("+"), lit("1")}, lit("1")}, "∀[]: Float", 0},
+		{"+", lit("+"), "∀[a]: Num a ⇒ a → a → a", 0},
+		{"λx. + x x", λ{"x", app{app{lit("+"), lit("x")}, lit("x")}}, "∀[a]: Num a ⇒ a → a", 0},
+		{"λx. λy. + x (id y)", λ{"x", λ{"y", app{app{lit("+"), lit("x")}, app{lit("id"), lit("y")}}}}, "∀[a]: Num a ⇒ a → a → a", 0},
+		{"λx. λy. (== x x, < y y)", λ{"x", λ{"y", app{app{lit("=="), app{app{lit("=="), lit("x")}, lit("x")}}, app{app{lit("<"), lit("y")}, lit("y")}}}}, "∀[a, b]: (Eq a, Ord b) ⇒ a → b → Bool", 0},
+		{"λx. (== x x, < x x)", λ{"x", app{app{lit("=="), app{app{lit("=="), lit("x")}, lit("x")}}, app{app{lit("<"), lit("x")}, lit("x")}}}, "∀[a]: Ord a ⇒ a → Bool", 0},
+		{"== nil nil", app{app{lit("=="), lit("nil")}, lit("nil")}, "", CodeAmbiguity},
+		{"λx. == (nil) x", λ{"x", app{app{lit("=="), lit("nil")}, lit("x")}}, "∀[a]: Eq a ⇒ List a → Bool", 0},
+		{"+ true true", app{app{lit("+"), lit("true")}, lit("true")}, "", CodeNoInstance},
+		{"λx. show (read x)", λ{"x", app{lit("show"), app{lit("read"), lit("x")}}}, "", CodeAmbiguity},
+
+		{"let double = λx. + x x in double 1",
+			let{"double", λ{"x", app{app{lit("+"), lit("x")}, lit("x")}}, app{lit("double"), lit("1")}},
+			"∀[]: Float", 0},
+		{"let double = λx. + x x in double true",
+			let{"double", λ{"x", app{app{lit("+"), lit("x")}, lit("x")}}, app{lit("double"), lit("true")}},
+			"", CodeNoInstance},
+		{"let double = λx. + x x in double",
+			let{"double", λ{"x", app{app{lit("+"), lit("x")}, lit("x")}}, lit("double")},
+			"∀[a]: Num a ⇒ a → a", 0},
+
+		// the predicate on y is not generalized in f, because y is bound outside of the let
+		{"λy. let f = λx. + x y in f",
+			λ{"y", let{"f", λ{"x", app{app{lit("+"), lit("x")}, lit("y")}}, lit("f")}},
+			"∀[a]: Num a ⇒ a → a → a", 0},
+		{"let f = λx. show (read x) in 1",
+			let{"f", λ{"x", app{lit("show"), app{lit("read"), lit("x")}}}, lit("1")},
+			"", CodeAmbiguity},
+	}
+
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		opts = append(opts, WithClasses(numClasses()))
+		for _, its := range inferClassTests {
+			sch, err := Infer(env, its.expr, opts...)
+			if its.err != 0 {
+				var te TypeError
+				if !errors.As(err, &te) || te.Code() != its.err {
+					t.Errorf("Test %q: Expected a %v error. Got %v, %v", its.name, its.err, sch, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("Test %q: %+v", its.name, err)
+				continue
+			}
+			if got := fmt.Sprintf("%v", sch); got != its.correct {
+				t.Errorf("Test %q: Expected %v. Got %v", its.name, its.correct, got)
+			}
+		}
+	}
+
+	// without classes, no predicate on concrete types can be resolved
+	if _, err := Infer(env, app{app{lit("+"), lit("1")}, lit("1")}); err == nil {
+		t.Error("Expected an error when there are no classes")
+	}
+}
@@ -4,7 +4,8 @@ import "fmt"
 
 // A Constraint is well.. a constraint that says a must equal to b. It's used mainly in the constraint generation process.
 type Constraint struct {
-	a, b Type
+	a, b   Type
+	origin *Origin
 }
 
 func (c Constraint) Apply(sub Subs) Substitutable {
@@ -23,3 +24,127 @@ func (c Constraint) FreeTypeVar() TypeVarSet {
 func (c Constraint) Format(state fmt.State, r rune) {
 	fmt.Fprintf(state, "{%v = %v}", c.a, c.b)
 }
+
+// fail creates the error for when the constraint cannot be solved. left and right are the two sides of the constraint,
+// with as much of the solution applied as is known at the point of failure.
+func (c Constraint) fail(left, right Type, err error) error {
+	if c.origin != nil && c.origin.Reason == ReasonIfBranches {
+		return withPos(c.origin, &BranchMismatchError{Then: left, Else: right, Origin: c.origin, Err: err})
+	}
+	return withPos(c.origin, &ConstraintError{Left: left, Right: right, Origin: c.origin, Err: err})
+}
+
+// Origin returns the origin of the constraint. It may be nil if the constraint wasn't generated by the inference algorithm
+func (c Constraint) Origin() *Origin { return c.origin }
+
+// Reason is the kind of Expression that caused a Constraint to be generated
+type Reason byte
+
+const (
+	NoReason          Reason = iota
+	ReasonApply              // the function of an application has to accept the argument
+	ReasonLet                // the constraint arises from the definition of a let
+	ReasonLetRec             // the constraint arises from the definition of a recursive let
+	ReasonSelect             // the record has to have the selected field
+	ReasonExtend             // only a record can be extended
+	ReasonRestrict           // the record has to have the field that is removed
+	ReasonPattern            // the pattern of an alternative has to match the scrutinee of the case
+	ReasonCase               // all the alternatives of a case have the same type
+	ReasonProject            // an element is projected from a record
+	ReasonIfCondition        // the condition of an if has to be a boolean
+	ReasonIfBranches         // both branches of an if have the same type
+	ReasonAnnotation         // the expression has to be as polymorphic as its annotation
+	ReasonCheck              // the expression has to be of the type it is checked against
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonApply:
+		return "application"
+	case ReasonLet:
+		return "definition of let"
+	case ReasonLetRec:
+		return "definition of letrec"
+	case ReasonSelect:
+		return "selection of field"
+	case ReasonExtend:
+		return "extension of record"
+	case ReasonRestrict:
+		return "restriction of record"
+	case ReasonPattern:
+		return "pattern"
+	case ReasonCase:
+		return "alternative of case"
+	case ReasonProject:
+		return "projection"
+	case ReasonIfCondition:
+		return "condition of if"
+	case ReasonIfBranches:
+		return "branches of if"
+	case ReasonAnnotation:
+		return "annotation"
+	case ReasonCheck:
+		return "expected type"
+	}
+	return "unknown"
+}
+
+// Origin records the provenance of a Constraint: the Expression that produced it, why it was produced,
+// and the origin of the constraint generated by the enclosing Expression.
+type Origin struct {
+	Expr   Expression
+	Reason Reason
+	Parent *Origin
+}
+
+// Pos returns the span of the innermost Expression in the origin chain that implements Positioner.
+func (o *Origin) Pos() (start, end Position) {
+	for ; o != nil; o = o.Parent {
+		if p, ok := o.Expr.(Positioner); ok {
+			if start, end = p.Pos(); start.IsValid() {
+				return
+			}
+		}
+	}
+	return
+}
+
+func (o *Origin) Format(s fmt.State, c rune) {
+	for i := o; i != nil; i = i.Parent {
+		if i != o {
+			s.Write([]byte("\n"))
+		}
+		fmt.Fprintf(s, "\tin %v", i.Reason)
+		if n, ok := i.Expr.(Namer); ok && i.Reason != ReasonApply {
+			fmt.Fprintf(s, " %v", n.Name())
+		}
+		if p, ok := i.Expr.(Positioner); ok {
+			if start, _ := p.Pos(); start.IsValid() {
+				fmt.Fprintf(s, " at %v", start)
+			}
+		}
+		fmt.Fprintf(s, ": %v", i.Expr)
+	}
+}
+
+// ConstraintError is returned when a Constraint cannot be solved.
+// Left and Right are the two sides of the constraint at the point of failure, and Err is the error returned by Unify,
+// which describes the sub-terms that failed to unify.
+type ConstraintError struct {
+	Left, Right Type
+	Origin      *Origin
+	Err         error
+}
+
+func (e *ConstraintError) Error() string {
+	if e.Origin == nil {
+		return fmt.Sprintf("Unable to solve {%v = %v}: %v", e.Left, e.Right, e.Err)
+	}
+	return fmt.Sprintf("Unable to solve {%v = %v}: %v\n%v", e.Left, e.Right, e.Err, e.Origin)
+}
+
+// Unwrap returns the underlying error. This allows the use of errors.Is and errors.As
+func (e *ConstraintError) Unwrap() error { return e.Err }
+
+// Cause returns the underlying error. This allows the use of errors.Cause
+func (e *ConstraintError) Cause() error { return e.Err }
@@ -1,6 +1,11 @@
 package hm
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
 
 func TestConstraint(t *testing.T) {
 	c := Constraint{
@@ -28,3 +33,70 @@ func TestConstraint(t *testing.T) {
 		t.Errorf("c.b: %v", c)
 	}
 }
+
+func TestConstraint_Origin(t *testing.T) {
+	env := SimpleEnv{
+		"+": &Scheme{tvs: TypeVarSet{'a'}, t: NewFnType(TypeVariable('a'), TypeVariable('a'), TypeVariable('a'))},
+	}
+
+	// let f = λn. (+ 1 true) in f
+	bad := app{app{lit("+"), lit("1")}, lit("true")}
+	expr := let{"f", λ{"n", bad}, lit("f")}
+
+	_, err := Infer(env, expr)
+	var ce *ConstraintError
+	if !errors.As(err, &ce) {
+		t.Fatalf("Expected a *ConstraintError. Got %v instead", err)
+	}
+
+	// which constraint failed
+	if !ce.Left.Eq(NewFnType(Float, Float)) {
+		t.Errorf("Expected the left side of the failed constraint to be Float → Float. Got %v", ce.Left)
+	}
+
+	// which sub-term failed
+	var ue UnificationError
+	if !errors.As(err, &ue) {
+		t.Fatalf("Expected a UnificationError. Got %v instead", err)
+	}
+	if ue.Left != Float || ue.Right != Bool {
+		t.Errorf("Expected Float ~ Bool to have failed. Got %v ~ %v", ue.Left, ue.Right)
+	}
+
+	// where the constraint came from
+	o := ce.Origin
+	if o == nil {
+		t.Fatal("Expected the failed constraint to have an origin")
+	}
+	if o.Reason != ReasonApply || o.Expr != bad {
+		t.Errorf("Expected the constraint to originate from %v. Got %v %v", bad, o.Reason, o.Expr)
+	}
+	if o.Parent == nil || o.Parent.Reason != ReasonLet || o.Parent.Expr != expr {
+		t.Fatalf("Expected the parent of the origin to be the let. Got %v", o.Parent)
+	}
+	if o.Parent.Parent != nil {
+		t.Errorf("Expected the let to be the root of the origin chain")
+	}
+
+	msg := err.Error()
+	if !strings.Contains(msg, "in application") || !strings.Contains(msg, "in definition of let f") {
+		t.Errorf("Expected the error message to report the origin chain. Got %q", msg)
+	}
+
+	// nested applications have their enclosing applications as parents
+	inner := app{lit("+"), lit("true")}
+	outer := posApp{app{lit("+"), app{inner, lit("1")}}, newSpan(4, 2, 10)}
+	_, err = Infer(env, outer)
+	if !errors.As(err, &ce) {
+		t.Fatalf("Expected a *ConstraintError. Got %v instead", err)
+	}
+	if ce.Origin.Expr != (app{inner, lit("1")}) || ce.Origin.Parent.Expr != outer {
+		t.Errorf("Unexpected origin chain %v", ce.Origin)
+	}
+
+	// and the innermost positioned origin is used as the position of the error
+	var pe *PositionError
+	if !errors.As(err, &pe) || pe.Start != outer.start {
+		t.Errorf("Expected the error to be positioned at %v. Got %v", outer.start, err)
+	}
+}
@@ -0,0 +1,105 @@
+package hm
+
+import "fmt"
+
+// ConstructedType is a type built by applying a named type constructor to type arguments. For example:
+//		NewConstructedType("Map", k, v)
+// is the type
+//		Map k v
+// Two ConstructedTypes only unify if they have the same constructor name and the same number of arguments.
+type ConstructedType struct {
+	name string
+	args Types
+}
+
+// NewConstructedType creates a new ConstructedType
+func NewConstructedType(name string, args ...Type) *ConstructedType {
+	return &ConstructedType{
+		name: name,
+		args: args,
+	}
+}
+
+// Args returns the type arguments of the ConstructedType
+func (t *ConstructedType) Args() Types { return t.args }
+
+func (t *ConstructedType) Name() string { return t.name }
+
+func (t *ConstructedType) Apply(sub Subs) Substitutable {
+	if sub == nil || len(t.args) == 0 {
+		return t
+	}
+	args := make(Types, len(t.args))
+	for i, a := range t.args {
+		args[i] = a.Apply(sub).(Type)
+	}
+	return NewConstructedType(t.name, args...)
+}
+
+func (t *ConstructedType) FreeTypeVar() TypeVarSet {
+	var tvs TypeVarSet
+	for _, a := range t.args {
+		tvs = a.FreeTypeVar().Union(tvs)
+	}
+	return tvs
+}
+
+func (t *ConstructedType) Normalize(k, v TypeVarSet) (Type, error) {
+	args := make(Types, len(t.args))
+	var err error
+	for i, a := range t.args {
+		if args[i], err = a.Normalize(k, v); err != nil {
+			return nil, err
+		}
+	}
+	return NewConstructedType(t.name, args...), nil
+}
+
+func (t *ConstructedType) Types() Types {
+	ts := BorrowTypes(len(t.args))
+	copy(ts, t.args)
+	return ts
+}
+
+func (t *ConstructedType) Eq(other Type) bool {
+	ot, ok := expandAlias(other).(*ConstructedType)
+	if !ok || ot.name != t.name || len(ot.args) != len(t.args) {
+		return false
+	}
+	for i, a := range t.args {
+		if !a.Eq(ot.args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Format formats the type. Arguments that are themselves made up of smaller types are parenthesized: Maybe (List a),
+// unless they are bracketed already, like records and rows are: Maybe (a, b)
+func (t *ConstructedType) Format(s fmt.State, c rune) {
+	s.Write([]byte(t.name))
+	for _, a := range t.args {
+		switch a.(type) {
+		case *Record, *Row:
+			fmt.Fprintf(s, " %v", a)
+			continue
+		}
+		if ts := a.Types(); len(ts) > 0 {
+			fmt.Fprintf(s, " (%v)", a)
+			ReturnTypes(ts)
+			continue
+		}
+		fmt.Fprintf(s, " %v", a)
+	}
+}
+
+func (t *ConstructedType) String() string { return fmt.Sprintf("%v", t) }
+
+// Clone implements Cloner
+func (t *ConstructedType) Clone() interface{} {
+	args := make(Types, len(t.args))
+	for i, a := range t.args {
+		args[i] = cloneType(a)
+	}
+	return NewConstructedType(t.name, args...)
+}
@@ -0,0 +1,139 @@
+package hm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestConstructedType(t *testing.T) {
+	k, v := TypeVariable('k'), TypeVariable('v')
+	m := NewConstructedType("Map", k, NewConstructedType("Maybe", v))
+	if fmt.Sprintf("%v", m) != "Map k (Maybe v)" {
+		t.Errorf("Wrong format. Got %v", m)
+	}
+	if fmt.Sprintf("%v", NewConstructedType("List", NewFnType(k, v))) != "List (k → v)" {
+		t.Errorf("Wrong format. Got %v", NewConstructedType("List", NewFnType(k, v)))
+	}
+	if got := fmt.Sprintf("%v", NewConstructedType("Maybe", NewRecordType("", k, v), NewRow(nil, Field{"x", k}))); got != "Maybe (k, v) {x: k}" {
+		t.Errorf("Expected records and rows to not be parenthesized again. Got %v", got)
+	}
+	if m.Name() != "Map" {
+		t.Errorf("Expected the name to be Map. Got %v", m.Name())
+	}
+
+	ftv := m.FreeTypeVar()
+	if !ftv.Equals(TypeVarSet{'k', 'v'}) {
+		t.Errorf("Expected ftv: {k, v}. Got %v", ftv)
+	}
+
+	ts := m.Types()
+	if len(ts) != 2 || ts[0] != k || !ts[1].Eq(NewConstructedType("Maybe", v)) {
+		t.Errorf("Wrong types: %v", ts)
+	}
+
+	m2 := m.Apply(mSubs{'v': proton}).(*ConstructedType)
+	if !m2.Eq(NewConstructedType("Map", k, NewConstructedType("Maybe", proton))) {
+		t.Errorf("Apply failed. Got %v", m2)
+	}
+	if !m.Eq(NewConstructedType("Map", k, NewConstructedType("Maybe", v))) {
+		t.Errorf("Apply should not change the original. Got %v", m)
+	}
+
+	n, err := m.Normalize(TypeVarSet{'k', 'v'}, TypeVarSet{'a', 'b'})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%v", n) != "Map a (Maybe b)" {
+		t.Errorf("Normalize failed. Got %v", n)
+	}
+
+	fnArg := NewConstructedType("List", NewFnType(k, v))
+	clone := fnArg.Clone().(*ConstructedType)
+	if !clone.Eq(fnArg) {
+		t.Errorf("Expected the clone to be equal")
+	}
+	clone.Args()[0].Apply(mSubs{'k': proton})
+	if !fnArg.Eq(NewConstructedType("List", NewFnType(k, v))) {
+		t.Errorf("Clone should be deep. Got %v", fnArg)
+	}
+
+	if m.Eq(NewConstructedType("Map", k)) || m.Eq(NewConstructedType("Dict", k, NewConstructedType("Maybe", v))) {
+		t.Error("Types with different constructors should not be equal")
+	}
+}
+
+var unifyConstructedTests = []struct {
+	name string
+	a, b Type
+	err  ErrorCode
+}{
+	{"Maybe a ~ Maybe proton", NewConstructedType("Maybe", TypeVariable('a')), NewConstructedType("Maybe", proton), 0},
+	{"Map a b ~ Map proton (Maybe c)", NewConstructedType("Map", TypeVariable('a'), TypeVariable('b')), NewConstructedType("Map", proton, NewConstructedType("Maybe", TypeVariable('c'))), 0},
+	{"Maybe a ~ List a", NewConstructedType("Maybe", TypeVariable('a')), NewConstructedType("List", TypeVariable('a')), CodeUnification},
+	{"Map a b ~ Map a", NewConstructedType("Map", TypeVariable('a'), TypeVariable('b')), NewConstructedType("Map", TypeVariable('a')), CodeUnification},
+	{"Maybe a ~ (a)", NewConstructedType("Maybe", TypeVariable('a')), NewRecordType("", TypeVariable('a')), CodeUnification},
+	{"Pair a a ~ a → a", NewConstructedType("Pair", TypeVariable('a'), TypeVariable('a')), NewFnType(TypeVariable('a'), TypeVariable('a')), CodeUnification},
+	{"Maybe a ~ a", NewConstructedType("Maybe", TypeVariable('a')), TypeVariable('a'), CodeOccursCheck},
+}
+
+func TestUnify_ConstructedType(t *testing.T) {
+	for _, uts := range unifyConstructedTests {
+		sub, err := Unify(uts.a, uts.b)
+
+		s := newUFSolver()
+		s.solve(Constraints{{a: uts.a, b: uts.b}})
+
+		for engine, err := range map[string]error{"Unify": err, "UnionFind": s.err} {
+			if uts.err == 0 {
+				if err != nil {
+					t.Errorf("%s %q: %v", engine, uts.name, err)
+				}
+				continue
+			}
+			var te TypeError
+			if !errors.As(err, &te) || te.Code() != uts.err {
+				t.Errorf("%s %q: Expected a %v error. Got %v", engine, uts.name, uts.err, err)
+			}
+		}
+
+		if uts.err == 0 {
+			a := cloneType(uts.a).Apply(sub).(Type)
+			b := cloneType(uts.b).Apply(sub).(Type)
+			if !a.Eq(b) {
+				t.Errorf("%q: Expected both sides to be equal after unification. Got %v and %v", uts.name, a, b)
+			}
+		}
+	}
+}
+
+func TestInfer_ConstructedType(t *testing.T) {
+	a := TypeVariable('a')
+	env := SimpleEnv{
+		"Just":    NewScheme(TypeVarSet{'a'}, NewFnType(a, NewConstructedType("Maybe", a))),
+		"Nothing": NewScheme(TypeVarSet{'a'}, NewConstructedType("Maybe", a)),
+		"fromMaybe": NewScheme(TypeVarSet{'a'},
+			NewFnType(a, NewConstructedType("Maybe", a), a)),
+	}
+
+	sch, err := Infer(env, λ{"x", app{lit("Just"), app{lit("Just"), lit("x")}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%v", sch) != "∀[a]: a → Maybe (Maybe a)" {
+		t.Errorf("Wrong scheme: %v", sch)
+	}
+
+	sch, err = Infer(env, app{app{lit("fromMaybe"), lit("1")}, lit("Nothing")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%v", sch) != "∀[]: Float" {
+		t.Errorf("Wrong scheme: %v", sch)
+	}
+
+	if _, err = Infer(env, app{app{lit("fromMaybe"), lit("1")}, app{lit("Just"), lit("true")}}); err == nil {
+		t.Error("Expected an error")
+	}
+}
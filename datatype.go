@@ -0,0 +1,67 @@
+package hm
+
+// DataType is an algebraic data type: a type constructor, its type parameters, and the constructors of its values.
+// For example, this:
+//		data Maybe a = Nothing | Just a
+// is
+//		&DataType{
+//			Name:   "Maybe",
+//			Params: TypeVarSet{'a'},
+//			Constructors: []Constructor{
+//				{Name: "Nothing"},
+//				{Name: "Just", Args: Types{TypeVariable('a')}},
+//			},
+//		}
+// The type of the values of a DataType is a ConstructedType: NewConstructedType(Name, Params...)
+type DataType struct {
+	Name         string
+	Params       TypeVarSet
+	Constructors []Constructor
+}
+
+// Constructor is a constructor of a DataType. The types of its arguments may refer to the parameters of the DataType.
+type Constructor struct {
+	Name string
+	Args Types
+}
+
+// constructor returns the constructor with the given name
+func (dt *DataType) constructor(name string) (Constructor, bool) {
+	for _, c := range dt.Constructors {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Constructor{}, false
+}
+
+// instantiate returns the type of the DataType and the types of the arguments of the constructor, with fresh type variables for the parameters.
+func (dt *DataType) instantiate(f Fresher, c Constructor) (Type, Types) {
+	sub := make(mSubs, len(dt.Params))
+	params := make(Types, len(dt.Params))
+	for i, p := range dt.Params {
+		fr := f.Fresh()
+		sub[p] = fr
+		params[i] = fr
+	}
+
+	args := make(Types, len(c.Args))
+	for i, a := range c.Args {
+		args[i] = cloneType(a).Apply(sub).(Type)
+	}
+	return NewConstructedType(dt.Name, params...), args
+}
+
+// WithDataTypes declares DataTypes, so that their constructors can be used in the patterns of Case expressions.
+func WithDataTypes(dts ...*DataType) InferOpt {
+	return func(infer *inferer) {
+		if infer.constructors == nil {
+			infer.constructors = make(map[string]*DataType)
+		}
+		for _, dt := range dts {
+			for _, c := range dt.Constructors {
+				infer.constructors[c.Name] = dt
+			}
+		}
+	}
+}
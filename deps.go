@@ -0,0 +1,194 @@
+package hm
+
+import "sort"
+
+// bindingGroups splits the bindings of a LetRecGroup into strongly connected components of the dependency graph.
+// The components are in dependency order: a component only refers to itself and the components before it.
+func bindingGroups(bindings []Binding) [][]Binding {
+	index := make(map[string]int, len(bindings))
+	for i, b := range bindings {
+		index[b.Name()] = i
+	}
+
+	deps := make([][]int, len(bindings))
+	for i, b := range bindings {
+		names := make(map[string]struct{})
+		freeNames(b.Body(), make(map[string]int), names)
+		for name := range names {
+			if j, ok := index[name]; ok {
+				deps[i] = append(deps[i], j)
+			}
+		}
+		sort.Ints(deps[i])
+	}
+
+	var retVal [][]Binding
+	for _, comp := range sccs(deps) {
+		sort.Ints(comp)
+		group := make([]Binding, len(comp))
+		for i, j := range comp {
+			group[i] = bindings[j]
+		}
+		retVal = append(retVal, group)
+	}
+	return retVal
+}
+
+// sccs finds the strongly connected components of a graph with Tarjan's algorithm.
+// edges[i] are the nodes that node i has an edge to.
+//
+// The components are returned in reverse topological order: every edge goes from a component to itself or to a component before it.
+func sccs(edges [][]int) [][]int {
+	n := len(edges)
+	index := make([]int, n) // 0 means unvisited, otherwise it's the visiting order + 1
+	low := make([]int, n)
+	onStack := make([]bool, n)
+	var stack []int
+	var count int
+	var retVal [][]int
+
+	var visit func(v int)
+	visit = func(v int) {
+		count++
+		index[v], low[v] = count, count
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range edges[v] {
+			switch {
+			case index[w] == 0:
+				visit(w)
+				if low[w] < low[v] {
+					low[v] = low[w]
+				}
+			case onStack[w] && index[w] < low[v]:
+				low[v] = index[w]
+			}
+		}
+
+		if low[v] == index[v] {
+			var comp []int
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				comp = append(comp, w)
+				if w == v {
+					break
+				}
+			}
+			retVal = append(retVal, comp)
+		}
+	}
+
+	for v := 0; v < n; v++ {
+		if index[v] == 0 {
+			visit(v)
+		}
+	}
+	return retVal
+}
+
+// freeNames adds the names that are referred to in the expression, but not bound in it, to names.
+// bound counts the binders of each name that are in scope.
+func freeNames(expr Expression, bound map[string]int, names map[string]struct{}) {
+	bind := func(ns ...string) func() {
+		for _, n := range ns {
+			bound[n]++
+		}
+		return func() {
+			for _, n := range ns {
+				bound[n]--
+			}
+		}
+	}
+
+	switch et := expr.(type) {
+	case nil:
+	case Literal:
+		if bound[et.Name()] == 0 {
+			names[et.Name()] = struct{}{}
+		}
+	case Var:
+		if bound[et.Name()] == 0 {
+			names[et.Name()] = struct{}{}
+		}
+	case Lambda:
+		unbind := bind(et.Name())
+		freeNames(et.Body(), bound, names)
+		unbind()
+	case LetRecGroup:
+		bs := et.Bindings()
+		ns := make([]string, len(bs))
+		for i, b := range bs {
+			ns[i] = b.Name()
+		}
+		unbind := bind(ns...)
+		for _, b := range bs {
+			freeNames(b.Body(), bound, names)
+		}
+		freeNames(et.Body(), bound, names)
+		unbind()
+	case LetRec:
+		unbind := bind(et.Name())
+		freeNames(et.Def(), bound, names)
+		freeNames(et.Body(), bound, names)
+		unbind()
+	case Let:
+		freeNames(et.Def(), bound, names)
+		unbind := bind(et.Name())
+		freeNames(et.Body(), bound, names)
+		unbind()
+	case Apply:
+		freeNames(et.Fn(), bound, names)
+		freeNames(et.Body(), bound, names)
+	case If:
+		freeNames(et.Cond(), bound, names)
+		freeNames(et.Then(), bound, names)
+		freeNames(et.Else(), bound, names)
+	case Tuple:
+		for _, e := range et.Elems() {
+			freeNames(e, bound, names)
+		}
+	case Extend:
+		freeNames(et.Body(), bound, names)
+		freeNames(et.Value(), bound, names)
+	case Select:
+		freeNames(et.Body(), bound, names)
+	case Restrict:
+		freeNames(et.Body(), bound, names)
+	case Project:
+		freeNames(et.Body(), bound, names)
+	case Annotated:
+		freeNames(et.Body(), bound, names)
+	case Case:
+		freeNames(et.Body(), bound, names)
+		for _, alt := range et.Alternatives() {
+			unbind := bind(patternNames(alt.Pattern(), nil)...)
+			freeNames(alt.Body(), bound, names)
+			unbind()
+		}
+	}
+}
+
+// patternNames appends the names of the variables bound by the pattern to names.
+func patternNames(p Pattern, names []string) []string {
+	switch pt := p.(type) {
+	case WildcardPattern, LiteralPattern:
+	case VarPattern:
+		names = append(names, pt.Name())
+	case ConstructorPattern:
+		for _, a := range pt.Args() {
+			names = patternNames(a, names)
+		}
+	case TuplePattern:
+		for _, e := range pt.Elems() {
+			names = patternNames(e, names)
+		}
+	case RecordPattern:
+		for _, e := range pt.Elems() {
+			names = patternNames(e, names)
+		}
+	}
+	return names
+}
@@ -0,0 +1,111 @@
+package hm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestSCCs(t *testing.T) {
+	// 0 → 1 ⇄ 2 → 3, 4 → 4
+	edges := [][]int{{1}, {2}, {1, 3}, nil, {4}}
+	got := fmt.Sprintf("%v", sccs(edges))
+	if correct := "[[3] [2 1] [0] [4]]"; got != correct {
+		t.Errorf("Expected %v. Got %v", correct, got)
+	}
+}
+
+func TestBindingGroups(t *testing.T) {
+	bindings := []Binding{
+		binding{"isEven", λ{"n", app{lit("isOdd"), lit("n")}}},
+		binding{"main", app{lit("isEven"), lit("1")}},
+		binding{"isOdd", λ{"n", app{lit("isEven"), lit("n")}}},
+		binding{"f", λ{"main", app{lit("main"), lit("g")}}}, // main is shadowed
+		binding{"g", lit("1")},
+	}
+
+	var names [][]string
+	for _, group := range bindingGroups(bindings) {
+		var ns []string
+		for _, b := range group {
+			ns = append(ns, b.Name())
+		}
+		names = append(names, ns)
+	}
+	got := fmt.Sprintf("%v", names)
+	if correct := "[[isEven isOdd] [main] [g] [f]]"; got != correct {
+		t.Errorf("Expected %v. Got %v", correct, got)
+	}
+}
+
+func TestInfer_LetRecGroup(t *testing.T) {
+	env := SimpleEnv{
+		"isZero": NewScheme(nil, NewFnType(Float, Bool)),
+		"pred":   NewScheme(nil, NewFnType(Float, Float)),
+	}
+
+	evenOdd := []Binding{
+		binding{"isEven", λ{"n", ifExpr{app{lit("isZero"), lit("n")}, lit("true"), app{lit("isOdd"), app{lit("pred"), lit("n")}}}}},
+		binding{"isOdd", λ{"n", ifExpr{app{lit("isZero"), lit("n")}, lit("false"), app{lit("isEven"), app{lit("pred"), lit("n")}}}}},
+	}
+
+	// g uses f at two different types
+	useF := λ{"y", ifExpr{app{lit("f"), lit("true")}, app{lit("f"), lit("1")}, lit("1")}}
+
+	groupTests := []struct {
+		name string
+		expr Expression
+
+		correct string
+		err     ErrorCode
+	}{
+		{"isEven/isOdd", letrecGroup{evenOdd, lit("isOdd")}, "∀[]: Float → Bool", 0},
+		{"polymorphic across components",
+			letrecGroup{[]Binding{binding{"g", useF}, binding{"f", λ{"x", lit("x")}}}, lit("g")},
+			"∀[a]: a → Float", 0},
+		{"monomorphic within a component",
+			letrecGroup{[]Binding{binding{"g", useF}, binding{"f", λ{"x", ifExpr{app{lit("g"), lit("x")}, lit("x"), lit("x")}}}}, lit("g")},
+			"", CodeUnification},
+		{"generalized before the body",
+			letrecGroup{[]Binding{binding{"id", λ{"x", lit("x")}}}, tuple{app{lit("id"), lit("1")}, app{lit("id"), lit("true")}}},
+			"∀[]: (Float, Bool)", 0},
+		{"recursive function",
+			letrecGroup{[]Binding{binding{"loop", λ{"x", app{lit("loop"), lit("x")}}}}, lit("loop")},
+			"∀[a, b]: a → b", 0},
+		{"annotated binding",
+			letrecGroup{[]Binding{
+				binding{"f", annotated{λ{"x", app{lit("g"), lit("x")}}, NewScheme(nil, NewFnType(Float, Float))}},
+				binding{"g", λ{"y", app{lit("f"), lit("y")}}},
+			}, lit("g")},
+			"∀[]: Float → Float", 0},
+		// g is monomorphic within the group, so the rigid a escapes through it
+		{"polymorphic annotated binding",
+			letrecGroup{[]Binding{
+				binding{"f", annotated{λ{"x", app{lit("g"), lit("x")}}, NewScheme(TypeVarSet{'a'}, NewFnType(TypeVariable('a'), TypeVariable('a')))}},
+				binding{"g", λ{"y", app{lit("f"), lit("y")}}},
+			}, lit("f")},
+			"", CodeSkolemEscape},
+	}
+
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		opts = append(opts, WithBoolType(Bool))
+		for _, gts := range groupTests {
+			sch, err := Infer(env, gts.expr, opts...)
+			if gts.err != 0 {
+				var te TypeError
+				if !errors.As(err, &te) || te.Code() != gts.err {
+					t.Errorf("Test %q: Expected a %v error. Got %v, %v", gts.name, gts.err, sch, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("Test %q: %+v", gts.name, err)
+				continue
+			}
+			if got := fmt.Sprintf("%v", sch); got != gts.correct {
+				t.Errorf("Test %q: Expected %v. Got %v", gts.name, gts.correct, got)
+			}
+		}
+	}
+}
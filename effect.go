@@ -0,0 +1,120 @@
+package hm
+
+import "fmt"
+
+// NewEffectRow creates a new effect row: a Row of effects. Each effect is a type, labelled by its name,
+// so effects with the same name are unified with each other. For example, this is the effect row {exn, state Int | e}:
+//		NewEffectRow(e, TypeConst("exn"), NewConstructedType("state", Int))
+// Like any other Row, an effect row is closed if it has no tail, and open if its tail is a TypeVariable.
+func NewEffectRow(tail Type, effects ...Type) *Row {
+	fields := make([]Field, len(effects))
+	for i, e := range effects {
+		fields[i] = Field{Label: e.Name(), Type: e}
+	}
+	return NewRow(tail, fields...)
+}
+
+// WithEffects makes the inference algorithm infer the effects of functions.
+//
+// The effects of the applications in the body of a Lambda are the effects of the Lambda: λs. print s is String →{io | e} (),
+// given that print is String →{io} (). The effect rows of the functions in the Env are opened when they are looked up,
+// so that a total function (or a function that only performs io) may be applied by a function that performs other effects as well.
+//
+// Without WithEffects, the effects of functions are not inferred, and the effect rows of the functions in the Env have to unify as they are.
+func WithEffects() InferOpt {
+	return func(infer *inferer) { infer.eff = infer.Fresh() }
+}
+
+// effectRow returns the effect row of a FunctionType. A total function has the empty effect row.
+func effectRow(eff Type) Type {
+	if eff == nil {
+		return &Row{}
+	}
+	return eff
+}
+
+// isEmptyRow returns true if the type is the closed Row without any fields
+func isEmptyRow(t Type) bool {
+	r, ok := t.(*Row)
+	return ok && len(r.fields) == 0 && r.tail == nil
+}
+
+// componentTypes returns the component types of two types of the same type constructor, so that they can be unified pairwise.
+// Only one of two FunctionTypes may have an effect row. The other one is total, so it's given the empty effect row.
+func componentTypes(a, b Type) (as, bs Types) {
+	as, bs = a.Types(), b.Types()
+	af, aIsFn := a.(*FunctionType)
+	_, bIsFn := b.(*FunctionType)
+	if aIsFn && bIsFn && len(as) != len(bs) {
+		if af.eff == nil {
+			as = append(as, &Row{})
+		} else {
+			bs = append(bs, &Row{})
+		}
+	}
+	return
+}
+
+// formatEffects formats the effect row of a FunctionType: {exn, io | e}. The labels of the effects are implied by their types.
+func formatEffects(s fmt.State, eff Type) {
+	r, ok := eff.(*Row)
+	if !ok {
+		fmt.Fprintf(s, "{| %v}", eff)
+		return
+	}
+	s.Write([]byte("{"))
+	for i, f := range r.fields {
+		if i > 0 {
+			s.Write([]byte(", "))
+		}
+		fmt.Fprintf(s, "%v", f.Type)
+	}
+	switch {
+	case r.tail == nil:
+	case len(r.fields) == 0:
+		fmt.Fprintf(s, "| %v", r.tail)
+	default:
+		fmt.Fprintf(s, " | %v", r.tail)
+	}
+	s.Write([]byte("}"))
+}
+
+// fnType creates the type of a function that is applied in the current Lambda: its effects are the effects of the Lambda.
+func (infer *inferer) fnType(a, b Type) *FunctionType {
+	ft := NewFnType(a, b)
+	ft.eff = infer.eff
+	return ft
+}
+
+// perform adds the constraint that the effects of the applied function are the effects of the current Lambda.
+func (infer *inferer) perform(ft *FunctionType, origin *Origin) {
+	if infer.eff == nil || ft.eff == nil {
+		return
+	}
+	infer.cs = append(infer.cs, Constraint{ft.eff, infer.eff, origin})
+}
+
+// openEffects opens the closed effect rows of a function and of the functions it returns, with fresh tails:
+// a → b →{io} c becomes a →{| e1} b →{io | e2} c. The effect rows of its arguments are left as they are.
+//
+// A function whose effect row is open may be applied wherever more effects are performed.
+func (infer *inferer) openEffects(t Type) Type {
+	ft, ok := t.(*FunctionType)
+	if !ok || infer.eff == nil {
+		return t
+	}
+	retVal := NewFnType(ft.a, infer.openEffects(ft.b))
+	switch eff := ft.eff.(type) {
+	case nil:
+		retVal.eff = NewRow(infer.Fresh())
+	case *Row:
+		if eff.tail == nil {
+			retVal.eff = &Row{fields: eff.fields, tail: infer.Fresh()}
+		} else {
+			retVal.eff = eff
+		}
+	default:
+		retVal.eff = eff
+	}
+	return retVal
+}
@@ -0,0 +1,127 @@
+package hm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestEffectfulFnType(t *testing.T) {
+	a, b, e := TypeVariable('a'), TypeVariable('b'), TypeVariable('e')
+	io, exn := TypeConst("io"), TypeConst("exn")
+
+	effectFormats := []struct {
+		t       Type
+		correct string
+	}{
+		{NewEffectfulFnType(NewEffectRow(e, io), a, b), "a →{io | e} b"},
+		{NewEffectfulFnType(NewEffectRow(nil, io, exn), a, b), "a →{exn, io} b"},
+		{NewEffectfulFnType(e, a, b), "a →{| e} b"},
+		{NewEffectfulFnType(NewEffectRow(nil, io), a, a, b), "a → a →{io} b"},
+		{NewEffectfulFnType(NewEffectRow(nil, NewConstructedType("state", a)), a, b), "a →{state a} b"},
+	}
+	for _, ef := range effectFormats {
+		if got := fmt.Sprintf("%v", ef.t); got != ef.correct {
+			t.Errorf("Expected %v. Got %v", ef.correct, got)
+		}
+	}
+
+	// a total function has the empty effect row
+	if !NewFnType(a, b).Eq(NewEffectfulFnType(NewEffectRow(nil), a, b)) {
+		t.Errorf("Expected a FunctionType without effects to be equal to one with the empty effect row")
+	}
+	if NewFnType(a, b).Eq(NewEffectfulFnType(NewEffectRow(nil, io), a, b)) {
+		t.Errorf("Expected a total FunctionType to not be equal to one that performs io")
+	}
+	if got := NewEffectfulFnType(e, a, b).Apply(mSubs{e: NewEffectRow(nil)}); got.(*FunctionType).Effects() != nil {
+		t.Errorf("Expected the empty effect row to be removed. Got %v", got)
+	}
+	if ftv := NewEffectfulFnType(e, a, b).FreeTypeVar(); !ftv.Equals(TypeVarSet{a, b, e}) {
+		t.Errorf("Expected the effect row to be part of the free TypeVariables. Got %v", ftv)
+	}
+}
+
+func TestUnify_Effects(t *testing.T) {
+	a, e := TypeVariable('a'), TypeVariable('e')
+	String, Unit := TypeConst("String"), TypeConst("Unit")
+	io, exn := TypeConst("io"), TypeConst("exn")
+
+	sub, err := Unify(NewEffectfulFnType(NewEffectRow(e, io), a, Unit), NewEffectfulFnType(NewEffectRow(nil, exn, io), String, Unit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := sub.Get(e); !ok || !got.Eq(NewEffectRow(nil, exn)) {
+		t.Errorf("Expected e to be {exn}. Got %v", got)
+	}
+
+	var te TypeError
+	if _, err = Unify(NewEffectfulFnType(NewEffectRow(nil, io), a, Unit), NewFnType(String, Unit)); !errors.As(err, &te) || te.Code() != CodeMissingField {
+		t.Errorf("Expected a function that performs io to not unify with a total function. Got %v", err)
+	}
+}
+
+func TestInfer_Effects(t *testing.T) {
+	a := TypeVariable('a')
+	String, Unit := TypeConst("String"), TypeConst("Unit")
+	io, exn := TypeConst("io"), TypeConst("exn")
+	env := SimpleEnv{
+		"print":  NewScheme(nil, NewEffectfulFnType(NewEffectRow(nil, io), String, Unit)),
+		"throw":  NewScheme(TypeVarSet{a}, NewEffectfulFnType(NewEffectRow(nil, exn), String, a)),
+		"concat": NewScheme(nil, NewFnType(String, String, String)),
+	}
+
+	effectTests := []struct {
+		name string
+		expr Expression
+
+		correct string
+	}{
+		{"λs. print s", λ{"s", app{lit("print"), lit("s")}}, "∀[a]: String →{io | a} Unit"},
+		{"λs. (λu. throw s) (print s)", λ{"s", app{λ{"u", app{lit("throw"), lit("s")}}, app{lit("print"), lit("s")}}}, "∀[a, b]: String →{exn, io | b} a"},
+		{"λf. λx. f x", λ{"f", λ{"x", app{lit("f"), lit("x")}}}, "∀[a, b, c, d]: (b →{| c} d) →{| a} b →{| c} d"},
+		{"λs. concat s s", λ{"s", app{app{lit("concat"), lit("s")}, lit("s")}}, "∀[a]: String →{| a} String"},
+		{"let p = λs. print s in p", let{"p", λ{"s", app{lit("print"), lit("s")}}, lit("p")}, "∀[a]: String →{io | a} Unit"},
+		{"print", lit("print"), "∀[a]: String →{io | a} Unit"},
+	}
+
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		opts = append(opts, WithEffects())
+		for _, et := range effectTests {
+			sch, err := Infer(env, et.expr, opts...)
+			if err != nil {
+				t.Errorf("Test %q: %v", et.name, err)
+				continue
+			}
+			if got := fmt.Sprintf("%v", sch); got != et.correct {
+				t.Errorf("Test %q: Expected %v. Got %v", et.name, et.correct, got)
+			}
+		}
+	}
+
+	// without WithEffects, the effects of functions are not inferred
+	sch, err := Infer(env, λ{"s", app{app{lit("concat"), lit("s")}, lit("s")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprintf("%v", sch); got != "∀[]: String → String" {
+		t.Errorf("Expected String → String. Got %v", got)
+	}
+}
+
+func TestCheck_Effects(t *testing.T) {
+	String, Unit := TypeConst("String"), TypeConst("Unit")
+	io := TypeConst("io")
+	env := SimpleEnv{
+		"print": NewScheme(nil, NewEffectfulFnType(NewEffectRow(nil, io), String, Unit)),
+	}
+	printS := λ{"s", app{lit("print"), lit("s")}}
+
+	if err := Check(env, printS, NewEffectfulFnType(NewEffectRow(nil, io), String, Unit), WithEffects()); err != nil {
+		t.Errorf("Expected λs. print s to be checked against String →{io} Unit. Got %v", err)
+	}
+	var te TypeError
+	if err := Check(env, printS, NewFnType(String, Unit), WithEffects()); !errors.As(err, &te) || te.Code() != CodeMissingField {
+		t.Errorf("Expected λs. print s to not be a total function. Got %v", err)
+	}
+}
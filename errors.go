@@ -0,0 +1,308 @@
+package hm
+
+import "fmt"
+
+// ErrorCode is a stable code that identifies the kind of error returned by this package.
+// The values of the codes will not change across versions, so it is safe to persist them or to map them to diagnostics of your own.
+type ErrorCode int
+
+const (
+	CodeUnification         ErrorCode = 1  // two types cannot be unified
+	CodeOccursCheck         ErrorCode = 2  // a type variable occurs in the type it is being bound to
+	CodeArityMismatch       ErrorCode = 3  // two composite types have different numbers of component types
+	CodeUndefinedName       ErrorCode = 4  // a name cannot be found in the Env
+	CodeUnhandledExpression ErrorCode = 5  // an Expression that cannot be handled by the inference algorithm
+	CodeNoInstance          ErrorCode = 6  // a predicate on concrete types has no instance
+	CodeAmbiguity           ErrorCode = 7  // a predicate constrains type variables that do not appear in the type
+	CodeMissingField        ErrorCode = 8  // a closed row does not have a field with the label
+	CodeNonExhaustive       ErrorCode = 9  // the patterns of a case do not match every value
+	CodeRedundantPattern    ErrorCode = 10 // a pattern of a case only matches values that are matched by the patterns before it
+	CodeProjection          ErrorCode = 11 // an element that a record does not have is projected
+	CodeBranchMismatch      ErrorCode = 12 // the branches of an if have different types
+	CodeRigidTypeVariable   ErrorCode = 13 // a quantified type variable of an annotation is unified with a type
+	CodeSkolemEscape        ErrorCode = 14 // a quantified type variable of an annotation escapes its scope
+	CodeKind                ErrorCode = 15 // a type is not of the kind it is expected to be
+	CodeUnboundParameter    ErrorCode = 16 // a type variable in a constructor of a DataType is not a parameter of the DataType
+	CodeSubtype             ErrorCode = 17 // a type is not a subtype of the type it is expected to be a subtype of
+)
+
+func (c ErrorCode) String() string {
+	switch c {
+	case CodeUnification:
+		return "unification"
+	case CodeOccursCheck:
+		return "occurs check"
+	case CodeArityMismatch:
+		return "arity mismatch"
+	case CodeUndefinedName:
+		return "undefined name"
+	case CodeUnhandledExpression:
+		return "unhandled expression"
+	case CodeNoInstance:
+		return "no instance"
+	case CodeAmbiguity:
+		return "ambiguity"
+	case CodeMissingField:
+		return "missing field"
+	case CodeNonExhaustive:
+		return "non-exhaustive patterns"
+	case CodeRedundantPattern:
+		return "redundant pattern"
+	case CodeProjection:
+		return "projection"
+	case CodeBranchMismatch:
+		return "branch mismatch"
+	case CodeRigidTypeVariable:
+		return "rigid type variable"
+	case CodeSkolemEscape:
+		return "skolem escape"
+	case CodeKind:
+		return "kind"
+	case CodeUnboundParameter:
+		return "unbound parameter"
+	case CodeSubtype:
+		return "subtype"
+	}
+	return fmt.Sprintf("ErrorCode(%d)", int(c))
+}
+
+// A TypeError is an error that carries a stable ErrorCode.
+// The errors that describe why an Expression is ill-typed are TypeErrors. They may be wrapped in a *PositionError or a *ConstraintError,
+// which add the context of the error but have no codes of their own, so the TypeError is extracted from the returned error with errors.As.
+// Errors that are not about the types of the program (a misused API, say) are not TypeErrors.
+type TypeError interface {
+	error
+	Code() ErrorCode
+}
+
+// UnificationError is returned when two types cannot be unified.
+type UnificationError struct {
+	Left, Right Type
+}
+
+func (e UnificationError) Error() string {
+	return fmt.Sprintf("Unification Fail: %v ~ %v cannot be unified", e.Left, e.Right)
+}
+
+// Code implements TypeError
+func (e UnificationError) Code() ErrorCode { return CodeUnification }
+
+// OccursCheckError is returned when a type variable is being bound to a type that contains the type variable itself.
+type OccursCheckError struct {
+	Var  TypeVariable
+	Type Type
+}
+
+func (e OccursCheckError) Error() string {
+	return fmt.Sprintf("recursive unification: %v occurs in %v", e.Var, e.Type)
+}
+
+// Code implements TypeError
+func (e OccursCheckError) Code() ErrorCode { return CodeOccursCheck }
+
+// ArityMismatchError is returned when the component types of two types cannot be unified pairwise because there are different numbers of them.
+type ArityMismatchError struct {
+	Left, Right Types
+}
+
+func (e ArityMismatchError) Error() string {
+	return fmt.Sprintf("Unequal length. a: %v b %v", e.Left, e.Right)
+}
+
+// Code implements TypeError
+func (e ArityMismatchError) Code() ErrorCode { return CodeArityMismatch }
+
+// UndefinedNameError is returned when a name cannot be found in the Env.
+type UndefinedNameError struct {
+	Name string
+}
+
+func (e UndefinedNameError) Error() string { return fmt.Sprintf("Undefined %v", e.Name) }
+
+// Code implements TypeError
+func (e UndefinedNameError) Code() ErrorCode { return CodeUndefinedName }
+
+// UnhandledExpressionError is returned when an Expression does not implement any of the Expression interfaces that the inference algorithm knows about.
+type UnhandledExpressionError struct {
+	Expr Expression
+}
+
+func (e UnhandledExpressionError) Error() string {
+	return fmt.Sprintf("Expression of %T is unhandled", e.Expr)
+}
+
+// Code implements TypeError
+func (e UnhandledExpressionError) Code() ErrorCode { return CodeUnhandledExpression }
+
+// NoInstanceError is returned when there is no instance of a class for the types of a predicate.
+type NoInstanceError struct {
+	Predicate Predicate
+}
+
+func (e NoInstanceError) Error() string { return fmt.Sprintf("No instance for %v", e.Predicate) }
+
+// Code implements TypeError
+func (e NoInstanceError) Code() ErrorCode { return CodeNoInstance }
+
+// AmbiguityError is returned when predicates cannot be resolved because they constrain type variables that do not appear in the inferred type.
+type AmbiguityError struct {
+	Predicates Predicates
+	Type       Type
+}
+
+func (e AmbiguityError) Error() string {
+	return fmt.Sprintf("Ambiguous type variables in %v for %v", e.Predicates, e.Type)
+}
+
+// Code implements TypeError
+func (e AmbiguityError) Code() ErrorCode { return CodeAmbiguity }
+
+// MissingFieldError is returned when a field is required of a closed row that doesn't have it.
+type MissingFieldError struct {
+	Label string
+	Row   *Row
+}
+
+func (e MissingFieldError) Error() string {
+	return fmt.Sprintf("Missing field: %v has no field %q", e.Row, e.Label)
+}
+
+// Code implements TypeError
+func (e MissingFieldError) Code() ErrorCode { return CodeMissingField }
+
+// NonExhaustiveError is returned when the patterns of a Case do not match every value.
+// Missing is an example of a value that is not matched.
+type NonExhaustiveError struct {
+	Missing string
+}
+
+func (e NonExhaustiveError) Error() string {
+	return fmt.Sprintf("Non-exhaustive patterns: %v is not matched", e.Missing)
+}
+
+// Code implements TypeError
+func (e NonExhaustiveError) Code() ErrorCode { return CodeNonExhaustive }
+
+// RedundantPatternError is returned when a pattern of a Case can never be matched, because the patterns before it match everything it matches.
+type RedundantPatternError struct {
+	Index   int // the index of the Alternative
+	Pattern string
+}
+
+func (e RedundantPatternError) Error() string {
+	return fmt.Sprintf("Redundant pattern: alternative %d (%v) is never matched", e.Index, e.Pattern)
+}
+
+// Code implements TypeError
+func (e RedundantPatternError) Code() ErrorCode { return CodeRedundantPattern }
+
+// ProjectionError is returned when an element is projected from a Record that doesn't have it,
+// or from a type that is not known to be a Record.
+type ProjectionError struct {
+	Type  Type
+	Index int
+	Label string
+}
+
+func (e ProjectionError) Error() string {
+	r, ok := e.Type.(*Record)
+	switch {
+	case !ok:
+		return fmt.Sprintf("Cannot project from %v: it is not known to be a record", e.Type)
+	case e.Label != "":
+		return fmt.Sprintf("Cannot project field %q of %v: it has no such field", e.Label, e.Type)
+	}
+	return fmt.Sprintf("Cannot project element %d of %v: it has %d elements", e.Index, e.Type, len(r.ts))
+}
+
+// Code implements TypeError
+func (e ProjectionError) Code() ErrorCode { return CodeProjection }
+
+// BranchMismatchError is returned when the then and else branches of an If have different types.
+// Err is the error returned by the unification of the types of the branches. Like a *ConstraintError, which it is returned instead of,
+// it is returned as a pointer.
+type BranchMismatchError struct {
+	Then, Else Type
+	Origin     *Origin
+	Err        error
+}
+
+func (e *BranchMismatchError) Error() string {
+	return fmt.Sprintf("The then and else branches have different types: %v and %v: %v\n%v", e.Then, e.Else, e.Err, e.Origin)
+}
+
+// Code implements TypeError
+func (e *BranchMismatchError) Code() ErrorCode { return CodeBranchMismatch }
+
+// Unwrap returns the underlying error. This allows the use of errors.Is and errors.As
+func (e *BranchMismatchError) Unwrap() error { return e.Err }
+
+// Cause returns the underlying error. This allows the use of errors.Cause
+func (e *BranchMismatchError) Cause() error { return e.Err }
+
+// RigidTypeVariableError is returned when a quantified type variable of an annotation is unified with a type other than itself.
+// This means that the annotated expression is not as polymorphic as its annotation.
+type RigidTypeVariableError struct {
+	Skolem Skolem
+	Type   Type
+}
+
+func (e RigidTypeVariableError) Error() string {
+	return fmt.Sprintf("%v is a rigid type variable of an annotation. It cannot be %v", e.Skolem, e.Type)
+}
+
+// Code implements TypeError
+func (e RigidTypeVariableError) Code() ErrorCode { return CodeRigidTypeVariable }
+
+// SkolemEscapeError is returned when a quantified type variable of an annotation escapes into the type of an enclosing expression.
+type SkolemEscapeError struct {
+	Skolem Skolem
+	Type   Type
+}
+
+func (e SkolemEscapeError) Error() string {
+	return fmt.Sprintf("The rigid type variable %v of an annotation escapes its scope in %v", e.Skolem, e.Type)
+}
+
+// Code implements TypeError
+func (e SkolemEscapeError) Code() ErrorCode { return CodeSkolemEscape }
+
+// KindError is returned when a type is not of the kind it is expected to be. For example, in List List,
+// the argument of the outer List is expected to be of kind *, but List is of kind * → *.
+type KindError struct {
+	Type             Type
+	Expected, Actual Kind
+}
+
+func (e KindError) Error() string {
+	return fmt.Sprintf("Kind mismatch in %v: expected a type of kind %v. Got %v", e.Type, e.Expected, e.Actual)
+}
+
+// Code implements TypeError
+func (e KindError) Code() ErrorCode { return CodeKind }
+
+// UnboundParameterError is returned when the argument of a constructor of a DataType has a type variable that is not a parameter of the DataType.
+type UnboundParameterError struct {
+	DataType, Constructor string
+	TypeVar               TypeVariable
+}
+
+func (e UnboundParameterError) Error() string {
+	return fmt.Sprintf("The type variable %v in the constructor %v is not a parameter of %v", e.TypeVar, e.Constructor, e.DataType)
+}
+
+// Code implements TypeError
+func (e UnboundParameterError) Code() ErrorCode { return CodeUnboundParameter }
+
+// SubtypeError is returned when a type has to be a subtype of another type, and it isn't in the Lattice.
+type SubtypeError struct {
+	Sub, Super Type
+	Origin     *Origin
+}
+
+func (e SubtypeError) Error() string {
+	return fmt.Sprintf("%v is not a subtype of %v\n%v", e.Sub, e.Super, e.Origin)
+}
+
+// Code implements TypeError
+func (e SubtypeError) Code() ErrorCode { return CodeSubtype }
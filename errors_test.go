@@ -0,0 +1,97 @@
+package hm
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestErrors_Unify(t *testing.T) {
+	var err error
+
+	_, err = Unify(proton, neutron)
+	var ue UnificationError
+	if !errors.As(err, &ue) {
+		t.Fatalf("Expected a UnificationError. Got %v instead", err)
+	}
+	if ue.Left != proton || ue.Right != neutron {
+		t.Errorf("Expected proton ~ neutron. Got %v ~ %v instead", ue.Left, ue.Right)
+	}
+	if ue.Code() != CodeUnification {
+		t.Errorf("Expected CodeUnification. Got %v", ue.Code())
+	}
+
+	_, err = Unify(TypeVariable('a'), NewFnType(TypeVariable('a'), proton))
+	var oe OccursCheckError
+	if !errors.As(err, &oe) {
+		t.Fatalf("Expected an OccursCheckError. Got %v instead", err)
+	}
+	if oe.Var != TypeVariable('a') || !oe.Type.Eq(NewFnType(TypeVariable('a'), proton)) {
+		t.Errorf("Unexpected OccursCheckError: %v", oe)
+	}
+
+	_, err = Unify(NewRecordType("", proton, neutron), NewRecordType("", proton, neutron, quark))
+	var ae ArityMismatchError
+	if !errors.As(err, &ae) {
+		t.Fatalf("Expected an ArityMismatchError. Got %v instead", err)
+	}
+	if len(ae.Left) != 2 || len(ae.Right) != 3 {
+		t.Errorf("Unexpected ArityMismatchError: %v", ae)
+	}
+
+	// every error is a TypeError
+	var te TypeError
+	if !errors.As(err, &te) || te.Code() != CodeArityMismatch {
+		t.Errorf("Expected a TypeError with CodeArityMismatch. Got %v", err)
+	}
+}
+
+func TestErrors_Infer(t *testing.T) {
+	env := SimpleEnv{
+		"+": &Scheme{tvs: TypeVarSet{'a'}, t: NewFnType(TypeVariable('a'), TypeVariable('a'), TypeVariable('a'))},
+	}
+
+	// undefined names are wrapped by the inference
+	_, err := Infer(env, λ{"x", app{lit("-"), lit("x")}})
+	var ne UndefinedNameError
+	if !errors.As(err, &ne) {
+		t.Fatalf("Expected an UndefinedNameError. Got %v instead", err)
+	}
+	if ne.Name != "-" {
+		t.Errorf("Expected the undefined name to be \"-\". Got %q instead", ne.Name)
+	}
+
+	_, err = Infer(env, app{lit("+"), unhandled{}})
+	var he UnhandledExpressionError
+	if !errors.As(err, &he) {
+		t.Fatalf("Expected an UnhandledExpressionError. Got %v instead", err)
+	}
+	if _, ok := he.Expr.(unhandled); !ok {
+		t.Errorf("Expected the unhandled expression to be recorded. Got %v", he.Expr)
+	}
+
+	_, err = Infer(env, app{app{lit("+"), lit("1")}, lit("true")})
+	var ue UnificationError
+	if !errors.As(err, &ue) {
+		t.Fatalf("Expected a UnificationError. Got %v instead", err)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	codes := map[ErrorCode]int{
+		CodeUnification:         1,
+		CodeOccursCheck:         2,
+		CodeArityMismatch:       3,
+		CodeUndefinedName:       4,
+		CodeUnhandledExpression: 5,
+	}
+	for c, v := range codes {
+		if int(c) != v {
+			t.Errorf("Error codes must be stable. Expected %v to be %d. Got %d", c, v, int(c))
+		}
+	}
+
+	if ErrorCode(1000).String() != "ErrorCode(1000)" {
+		t.Errorf("Unexpected String() of unknown error code: %v", ErrorCode(1000))
+	}
+}
@@ -0,0 +1,90 @@
+package hm
+
+// WithValueRestriction makes the inference algorithm only generalize the definitions of lets (and letrecs) that are not expansive.
+// The classifier decides which Expressions are expansive: the ones whose evaluation may create a mutable reference, or perform any other effect.
+// If the classifier is nil, Expansive is used.
+//
+// Without the value restriction, a polymorphic ref makes the type system unsound:
+//		let r = ref [] in (r := [1], r := [true])
+// The type of r is generalized to ∀a. Ref [a], so both assignments type check.
+// With the value restriction, ref [] is expansive, so r is Ref [a] for some a, and the assignments are a type error.
+//
+// The TypeVariables of an expansive definition are not generalized, but they are still inferred from the uses of the definition.
+// If the expression given to Infer is expansive, the scheme that it returns is not generalized either: its TypeVariables are free.
+func WithValueRestriction(classifier func(Expression) bool) InferOpt {
+	return func(infer *inferer) {
+		if classifier == nil {
+			classifier = Expansive
+		}
+		infer.expansive = classifier
+	}
+}
+
+// Expansive is the syntactic classifier of the value restriction. An Expression is not expansive if it is a value:
+// a variable, a literal, a Lambda, or a Tuple or record of values. Lets, Ifs and Cases of values are not expansive either.
+// Applications are expansive, as are the Expressions that the classifier doesn't know about.
+//
+// A classifier that knows more about the expressions may be written on top of Expansive. For example,
+// the application of a data constructor is not expansive if its argument isn't.
+func Expansive(expr Expression) bool {
+	switch et := expr.(type) {
+	case Typer, Lambda:
+		return false
+	case Apply:
+		return true
+	case LetRecGroup:
+		for _, b := range et.Bindings() {
+			if Expansive(b.Body()) {
+				return true
+			}
+		}
+		return Expansive(et.Body())
+	case Let:
+		return Expansive(et.Def()) || Expansive(et.Body())
+	case Annotated:
+		return Expansive(et.Body())
+	case Extend:
+		return Expansive(et.Value()) || Expansive(et.Body())
+	case Select:
+		return Expansive(et.Body())
+	case Restrict:
+		return Expansive(et.Body())
+	case If:
+		return Expansive(et.Cond()) || Expansive(et.Then()) || Expansive(et.Else())
+	case Tuple:
+		for _, e := range et.Elems() {
+			if Expansive(e) {
+				return true
+			}
+		}
+		return false
+	case Project:
+		return Expansive(et.Body())
+	case Case:
+		for _, alt := range et.Alternatives() {
+			if Expansive(alt.Body()) {
+				return true
+			}
+		}
+		return Expansive(et.Body())
+	}
+	return true
+}
+
+// restrict keeps the TypeVariables of expansive definitions from being generalized: the TypeVariables introduced since the let are moved to its level.
+func (infer *inferer) restrict(defs ...Expression) {
+	if infer.expansive == nil {
+		return
+	}
+	for _, def := range defs {
+		if !infer.expansive(def) {
+			continue
+		}
+		for tv, lvl := range infer.levels {
+			if lvl > infer.level {
+				infer.levels[tv] = infer.level
+			}
+		}
+		return
+	}
+}
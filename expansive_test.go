@@ -0,0 +1,112 @@
+package hm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestExpansive(t *testing.T) {
+	expansiveTests := []struct {
+		name string
+		expr Expression
+
+		correct bool
+	}{
+		{"x", lit("x"), false},
+		{"1", lit("1"), false},
+		{"λx. ref x", λ{"x", app{lit("ref"), lit("x")}}, false},
+		{"ref x", app{lit("ref"), lit("x")}, true},
+		{"(x, λy. y)", tuple{lit("x"), λ{"y", lit("y")}}, false},
+		{"(x, ref x)", tuple{lit("x"), app{lit("ref"), lit("x")}}, true},
+		{"let y = x in λz. y", let{"y", lit("x"), λ{"z", lit("y")}}, false},
+		{"let y = ref x in y", let{"y", app{lit("ref"), lit("x")}, lit("y")}, true},
+		{"{a = x | {}}.a", sel{ext{emptyRec{}, "a", lit("x")}, "a"}, false},
+		{"unhandled", unhandled{}, true},
+	}
+	for _, et := range expansiveTests {
+		if got := Expansive(et.expr); got != et.correct {
+			t.Errorf("Test %q: Expected Expansive to be %t", et.name, et.correct)
+		}
+	}
+}
+
+func TestInfer_ValueRestriction(t *testing.T) {
+	a := TypeVariable('a')
+	Unit := TypeConst("Unit")
+	ref := func(t Type) Type { return NewConstructedType("Ref", t) }
+	list := func(t Type) Type { return NewConstructedType("List", t) }
+	env := SimpleEnv{
+		"ref":    NewScheme(TypeVarSet{a}, NewFnType(a, ref(a))),
+		"assign": NewScheme(TypeVarSet{a}, NewFnType(ref(a), a, Unit)),
+		"nil":    NewScheme(TypeVarSet{a}, list(a)),
+		"single": NewScheme(TypeVarSet{a}, NewFnType(a, list(a))),
+		"id":     NewScheme(TypeVarSet{a}, NewFnType(a, a)),
+	}
+
+	// let r = ref nil in (assign r (single 1), assign r (single true))
+	assignBoth := let{"r", app{lit("ref"), lit("nil")}, tuple{
+		app{app{lit("assign"), lit("r")}, app{lit("single"), lit("1")}},
+		app{app{lit("assign"), lit("r")}, app{lit("single"), lit("true")}},
+	}}
+	// let id2 = id id in (id2 1, id2 true)
+	etaReduced := let{"id2", app{lit("id"), lit("id")}, tuple{app{lit("id2"), lit("1")}, app{lit("id2"), lit("true")}}}
+
+	// applications of id are as expansive as their arguments
+	idIsPure := func(expr Expression) bool {
+		if ap, ok := expr.(Apply); ok {
+			if fn, ok := ap.Fn().(Namer); ok && fn.Name() == "id" {
+				return Expansive(ap.Body())
+			}
+		}
+		return Expansive(expr)
+	}
+
+	restrictionTests := []struct {
+		name       string
+		expr       Expression
+		classifier func(Expression) bool
+
+		correct string
+		err     bool
+	}{
+		{"let r = ref nil in (assign r (single 1), assign r (single true))", assignBoth, nil, "", true},
+		{"let r = ref nil in r", let{"r", app{lit("ref"), lit("nil")}, lit("r")}, nil, "∀[]: Ref (List a)", false},
+		{"ref nil", app{lit("ref"), lit("nil")}, nil, "∀[]: Ref (List a)", false},
+		{"λx. ref x", λ{"x", app{lit("ref"), lit("x")}}, nil, "∀[a]: a → Ref a", false},
+		{"let f = λx. x in (f 1, f true)", let{"f", λ{"x", lit("x")}, tuple{app{lit("f"), lit("1")}, app{lit("f"), lit("true")}}}, nil, "∀[]: (Float, Bool)", false},
+		{"letrec f = λx. x in (f 1, f true)", letrec{"f", λ{"x", lit("x")}, tuple{app{lit("f"), lit("1")}, app{lit("f"), lit("true")}}}, nil, "∀[]: (Float, Bool)", false},
+		{"let id2 = id id in (id2 1, id2 true)", etaReduced, nil, "", true},
+		{"let id2 = id id in (id2 1, id2 true) (id is pure)", etaReduced, idIsPure, "∀[]: (Float, Bool)", false},
+	}
+
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		for _, rt := range restrictionTests {
+			sch, err := Infer(env, rt.expr, append(opts, WithValueRestriction(rt.classifier))...)
+			if rt.err {
+				var te TypeError
+				if !errors.As(err, &te) || te.Code() != CodeUnification {
+					t.Errorf("Test %q: Expected a unification error. Got %v, %v", rt.name, sch, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("Test %q: %v", rt.name, err)
+				continue
+			}
+			if got := fmt.Sprintf("%v", sch); got != rt.correct {
+				t.Errorf("Test %q: Expected %v. Got %v", rt.name, rt.correct, got)
+			}
+		}
+	}
+
+	// without the value restriction, the ref is polymorphic
+	sch, err := Infer(env, assignBoth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprintf("%v", sch); got != "∀[]: (Unit, Unit)" {
+		t.Errorf("Expected (Unit, Unit). Got %v", got)
+	}
+}
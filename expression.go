@@ -53,9 +53,142 @@ type Let interface {
 	Def() Expression
 }
 
+// LetRecGroup is an Expression/AST node that represents a group of bindings that may refer to each other:
+//		let rec f = e1 and g = e2 ... in body
+// The body is the Body().
+type LetRecGroup interface {
+	Expression
+	Bindings() []Binding
+}
+
+// Binding is a binding of a LetRecGroup. Its Body() is the definition of the name.
+type Binding interface {
+	Expression
+	Namer
+}
+
 // Lambda is an Expression/AST node that represents a function definiton
 type Lambda interface {
 	Expression
 	Namer
 	IsLambda() bool
 }
+
+// TypedLambda is a Lambda whose parameter is annotated with a type: λ(x : t). body
+// Unlike the type variables of an Annotated, the type variables of the ParamType() are not rigid: they may be inferred to be any type.
+type TypedLambda interface {
+	Lambda
+	ParamType() Type
+}
+
+// Annotated is an Expression/AST node that represents an expression annotated with a type written by the user: (e : σ).
+// The annotated expression is the Body().
+//
+// The inferred type of the expression has to be at least as polymorphic as the Annotation(): its quantified type variables are rigid.
+// The type variables that are free in the Annotation() are quantified implicitly. An annotation with a monotype is NewScheme(nil, t).
+type Annotated interface {
+	Expression
+	Annotation() *Scheme
+}
+
+// Select is an Expression/AST node that represents the selection of a field of a record: r.label.
+// The record is the Body()
+type Select interface {
+	Expression
+	Label() string
+	IsSelect() bool
+}
+
+// Extend is an Expression/AST node that represents the extension of a record with a field: {label = value | r}.
+// The record being extended is the Body(). If the record already has a field with the same label, the new field shadows it.
+type Extend interface {
+	Expression
+	Label() string
+	Value() Expression
+}
+
+// Restrict is an Expression/AST node that represents the removal of a field from a record: r - label.
+// The record is the Body(). If the record has many fields with the same label, only the first one is removed.
+type Restrict interface {
+	Expression
+	Label() string
+	IsRestrict() bool
+}
+
+// If is an Expression/AST node that represents a conditional: if cond then a else b.
+// The condition has to be a boolean, and both branches have to be of the same type.
+type If interface {
+	Expression
+	Cond() Expression
+	Then() Expression
+	Else() Expression
+}
+
+// Tuple is an Expression/AST node that represents the construction of a tuple: (e1, e2 ...).
+// The type of a Tuple is a Record without a name or labels.
+type Tuple interface {
+	Expression
+	Elems() []Expression
+}
+
+// Project is an Expression/AST node that represents the projection of an element of a Record.
+// The record is the Body(). If the Label() is not empty, the field with that label is projected.
+// Otherwise the element at Index() is projected.
+type Project interface {
+	Expression
+	Index() int
+	Label() string
+}
+
+// Case is an Expression/AST node that represents pattern matching:
+//		case e of p1 → e1; p2 → e2 ...
+// The scrutinee (e) is the Body().
+type Case interface {
+	Expression
+	Alternatives() []Alternative
+}
+
+// Alternative is an alternative of a Case. Its Body() is evaluated when the scrutinee matches its Pattern().
+type Alternative interface {
+	Expression
+	Pattern() Pattern
+}
+
+// Pattern is a pattern of an Alternative. A Pattern has to be one of the following:
+//		WildcardPattern, VarPattern, LiteralPattern, ConstructorPattern, RecordPattern, TuplePattern
+type Pattern interface{}
+
+// WildcardPattern is a Pattern that matches anything: _
+type WildcardPattern interface {
+	IsWildcard() bool
+}
+
+// VarPattern is a Pattern that matches anything, and binds it to its name
+type VarPattern interface {
+	Namer
+	IsVarPattern() bool
+}
+
+// LiteralPattern is a Pattern that matches a literal. If its Type() is nil, the type is looked up in the Env by its name.
+type LiteralPattern interface {
+	Namer
+	Typer
+	IsLit() bool
+}
+
+// ConstructorPattern is a Pattern that matches the values built by a constructor of a DataType: Just x
+type ConstructorPattern interface {
+	Namer
+	Args() []Pattern
+}
+
+// TuplePattern is a Pattern that matches a tuple (a Record without labels): (p1, p2)
+type TuplePattern interface {
+	Elems() []Pattern
+}
+
+// RecordPattern is a Pattern that matches a record that has (at least) the labelled fields: {x = p1, y = p2}
+type RecordPattern interface {
+	TuplePattern
+	Labels() []string
+}
@@ -0,0 +1,209 @@
+package hm
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ForallType is a polymorphic type that may appear inside other types: ∀a. a → a.
+// With ForallTypes as the parameters of functions, the type of a function can require its argument to be polymorphic:
+//		(∀a. a → a) → (Float, Bool)
+//
+// ForallTypes are never inferred. They come from annotations (Annotated, TypedLambda, Check) and from the types in the Env.
+// An argument is checked against a polymorphic parameter by replacing the quantified TypeVariables with Skolems,
+// and a value of a ForallType is instantiated with fresh TypeVariables wherever it is used.
+type ForallType struct {
+	tvs TypeVarSet
+	t   Type
+}
+
+// NewForallType creates a new ForallType that quantifies the TypeVariables over t.
+func NewForallType(tvs TypeVarSet, t Type) *ForallType {
+	return &ForallType{
+		tvs: tvs,
+		t:   t,
+	}
+}
+
+// TypeVars returns the quantified TypeVariables
+func (t *ForallType) TypeVars() TypeVarSet { return t.tvs }
+
+// Body returns the type that the TypeVariables are quantified over
+func (t *ForallType) Body() Type { return t.t }
+
+func (t *ForallType) Name() string { return "∀" }
+
+// Apply applies the substitution to the body. The quantified TypeVariables are not substituted,
+// and they are renamed if they would capture the free TypeVariables of the substituted types.
+func (t *ForallType) Apply(sub Subs) Substitutable {
+	if sub == nil {
+		return t
+	}
+	sub = sub.Clone()
+	defer ReturnSubs(sub)
+	for _, tv := range t.tvs {
+		sub = sub.Remove(tv)
+	}
+
+	tvs := append(TypeVarSet(nil), t.tvs...)
+	body := cloneType(t.t)
+	var used TypeVarSet
+	for _, s := range sub.Iter() {
+		for _, ftv := range s.T.FreeTypeVar() {
+			if i := tvs.Index(ftv); i >= 0 {
+				if used == nil {
+					used = boundUsed(t.tvs, t.t, sub)
+				}
+				fresh := unusedTypeVars(used, 1)[0]
+				used = append(used, fresh)
+				body = body.Apply(mSubs{tvs[i]: fresh}).(Type)
+				tvs[i] = fresh
+			}
+		}
+	}
+	return &ForallType{tvs: tvs, t: body.Apply(sub).(Type)}
+}
+
+func (t *ForallType) FreeTypeVar() TypeVarSet {
+	return t.t.FreeTypeVar().Difference(append(TypeVarSet(nil), t.tvs...))
+}
+
+// Normalize normalizes the free TypeVariables of the body with k and v. The quantified TypeVariables are named after the names in v.
+func (t *ForallType) Normalize(k, v TypeVarSet) (Type, error) {
+	// the quantified TypeVariables come first, as they shadow the TypeVariables of the enclosing types
+	tvs := unusedTypeVars(v, len(t.tvs))
+	k2 := append(append(TypeVarSet(nil), t.tvs...), k...)
+	v2 := append(append(TypeVarSet(nil), tvs...), v...)
+
+	body, err := t.t.Normalize(k2, v2)
+	if err != nil {
+		return nil, err
+	}
+	return &ForallType{tvs: tvs, t: body}, nil
+}
+
+func (t *ForallType) Types() Types { return Types{t.t} }
+
+// Eq returns true if the types are the same up to the names of the quantified TypeVariables.
+func (t *ForallType) Eq(other Type) bool {
+	ot, ok := expandAlias(other).(*ForallType)
+	if !ok {
+		return false
+	}
+	as, bs, _, err := skolemizeForalls(newTypesFresher(t, ot), t, ot)
+	return err == nil && as.Eq(bs)
+}
+
+func (t *ForallType) Format(s fmt.State, c rune) {
+	var buf bytes.Buffer
+	buf.WriteString("∀")
+	for i, tv := range t.tvs {
+		if i > 0 {
+			buf.WriteString(" ")
+		}
+		fmt.Fprintf(&buf, "%v", tv)
+	}
+	fmt.Fprintf(&buf, ". %v", t.t)
+	s.Write(buf.Bytes())
+}
+
+func (t *ForallType) String() string { return fmt.Sprintf("%v", t) }
+
+// Clone implements Cloner
+func (t *ForallType) Clone() interface{} {
+	return &ForallType{
+		tvs: append(TypeVarSet(nil), t.tvs...),
+		t:   cloneType(t.t),
+	}
+}
+
+// hasForall returns true if there is a ForallType in t
+func hasForall(t Type) bool {
+	switch tt := t.(type) {
+	case *ForallType:
+		return true
+	case *AliasType:
+		if e := tt.Expand(); e != t {
+			return hasForall(e)
+		}
+	}
+	for _, c := range t.Types() {
+		if hasForall(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// unifyForalls unifies two ForallTypes. They unify if their bodies unify with the quantified TypeVariables replaced by the same Skolems,
+// and none of the Skolems escape into the substitution.
+func unifyForalls(f Fresher, a, b *ForallType) (Subs, error) {
+	as, bs, sks, err := skolemizeForalls(f, a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := unify(f, as, bs)
+	if err != nil {
+		return nil, err
+	}
+	if sub != nil {
+		for _, s := range sub.Iter() {
+			if sk, ok := mentions(s.T, sks); ok {
+				return nil, SkolemEscapeError{Skolem: sk, Type: s.T}
+			}
+		}
+	}
+	return sub, nil
+}
+
+// skolemizeForalls replaces the quantified TypeVariables of both ForallTypes with the same Skolems.
+func skolemizeForalls(f Fresher, a, b *ForallType) (as, bs Type, sks []Skolem, err error) {
+	if len(a.tvs) != len(b.tvs) {
+		return nil, nil, nil, UnificationError{a, b}
+	}
+	asub := make(mSubs, len(a.tvs))
+	bsub := make(mSubs, len(b.tvs))
+	sks = make([]Skolem, len(a.tvs))
+	for i, tv := range a.tvs {
+		sks[i] = freshSkolem(f, tv)
+		asub[tv] = sks[i]
+		bsub[b.tvs[i]] = sks[i]
+	}
+	return cloneType(a.t).Apply(asub).(Type), cloneType(b.t).Apply(bsub).(Type), sks, nil
+}
+
+// instantiateForall instantiates the quantified TypeVariables of t with fresh TypeVariables, if t is a ForallType.
+func (infer *inferer) instantiateForall(t Type) Type {
+	for {
+		ft, ok := expandAlias(t).(*ForallType)
+		if !ok {
+			return t
+		}
+		t, _ = instantiate(infer, &Scheme{tvs: ft.tvs, t: ft.t})
+	}
+}
+
+// noteForalls notes that t has a ForallType in it. From then on, the types of functions are resolved eagerly to find their polymorphic parameters.
+func (infer *inferer) noteForalls(t Type) {
+	if !infer.rankN && t != nil && hasForall(t) {
+		infer.rankN = true
+	}
+}
+
+// checkForall checks that the expression is as polymorphic as the ForallType:
+// the expression has to be of the body of the ForallType, with the quantified TypeVariables replaced by Skolems.
+func (infer *inferer) checkForall(expr Expression, expected *ForallType) error {
+	start := len(infer.ps)
+	infer.level++
+	skolemized, _, sks := infer.skolemize(&Scheme{tvs: expected.tvs, t: expected.t})
+	if err := infer.check(expr, skolemized); err != nil {
+		return err
+	}
+	infer.level--
+	if err := infer.checkSkolems(sks, nil, start); err != nil {
+		return err
+	}
+	infer.t = expected
+	return nil
+}
@@ -0,0 +1,152 @@
+package hm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestForallType(t *testing.T) {
+	a, b, c := TypeVariable('a'), TypeVariable('b'), TypeVariable('c')
+	id := NewForallType(TypeVarSet{'a'}, NewFnType(a, a))
+
+	if got := fmt.Sprintf("%v", NewFnType(id, NewRecordType("", Float, Bool))); got != "(∀a. a → a) → (Float, Bool)" {
+		t.Errorf("Expected (∀a. a → a) → (Float, Bool). Got %v", got)
+	}
+	if got := fmt.Sprintf("%v", NewFnType(NewFnType(a, b), c)); got != "(a → b) → c" {
+		t.Errorf("Expected (a → b) → c. Got %v", got)
+	}
+
+	// the quantified TypeVariables are not free, and are not substituted
+	f := NewForallType(TypeVarSet{'a'}, NewFnType(a, b))
+	if ftv := f.FreeTypeVar(); len(ftv) != 1 || ftv[0] != b {
+		t.Errorf("Expected only b to be free. Got %v", ftv)
+	}
+	applied := f.Apply(mSubs{'a': proton, 'b': neutron}).(*ForallType)
+	if got := fmt.Sprintf("%v", applied); got != "∀a. a → neutron" {
+		t.Errorf("Expected ∀a. a → neutron. Got %v", got)
+	}
+	if got := fmt.Sprintf("%v", f); got != "∀a. a → b" {
+		t.Errorf("Apply should not mutate the ForallType. Got %v", got)
+	}
+
+	// the quantified TypeVariables are renamed instead of capturing the free TypeVariables of the substitution
+	captured := f.Apply(mSubs{'b': a}).(*ForallType)
+	if got := fmt.Sprintf("%v", captured); got != "∀c. c → a" {
+		t.Errorf("Expected the quantified a to be renamed to the first unused TypeVariable: ∀c. c → a. Got %v", got)
+	}
+
+	if !id.Eq(NewForallType(TypeVarSet{'b'}, NewFnType(b, b))) {
+		t.Errorf("ForallTypes that only differ in the names of the quantified TypeVariables should be equal")
+	}
+	if id.Eq(NewForallType(TypeVarSet{'b'}, NewFnType(b, a))) {
+		t.Errorf("∀a. a → a should not be equal to ∀b. b → a")
+	}
+
+	sc := NewScheme(TypeVarSet{'c'}, NewFnType(NewForallType(TypeVarSet{'a'}, NewFnType(a, c)), c))
+	if err := sc.Normalize(); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprintf("%v", sc); got != "∀[a]: (∀b. b → a) → a" {
+		t.Errorf("Expected ∀[a]: (∀b. b → a) → a. Got %v", got)
+	}
+}
+
+func TestUnifyForalls(t *testing.T) {
+	a, b, c := TypeVariable('a'), TypeVariable('b'), TypeVariable('c')
+	id := NewForallType(TypeVarSet{'a'}, NewFnType(a, a))
+
+	if sub, err := Unify(id, NewForallType(TypeVarSet{'b'}, NewFnType(b, b))); err != nil || sub != nil {
+		t.Errorf("Expected ∀a. a → a ~ ∀b. b → b without substitutions. Got %v, %v", sub, err)
+	}
+	if _, err := Unify(id, NewForallType(TypeVarSet{'b'}, NewFnType(b, Float))); err == nil {
+		t.Errorf("Expected ∀a. a → a ~ ∀b. b → Float to fail")
+	}
+	var se SkolemEscapeError
+	if _, err := Unify(id, NewForallType(TypeVarSet{'b'}, NewFnType(b, c))); !errors.As(err, &se) {
+		t.Errorf("Expected a SkolemEscapeError. Got %v", err)
+	}
+	if _, err := Unify(id, NewFnType(c, c)); err == nil {
+		t.Errorf("Expected ∀a. a → a ~ c → c to fail")
+	}
+	if sub, err := Unify(c, id); err != nil || sub == nil {
+		t.Errorf("Expected c to be bound to ∀a. a → a. Got %v, %v", sub, err)
+	}
+}
+
+func TestInfer_HigherRank(t *testing.T) {
+	a, s := TypeVariable('a'), TypeVariable('s')
+	b := TypeVariable('b')
+	st := func(s, a Type) Type { return NewConstructedType("ST", s, a) }
+	ref := func(s, a Type) Type { return NewConstructedType("Ref", s, a) }
+	id := NewForallType(TypeVarSet{'a'}, NewFnType(a, a))
+	pair := NewRecordType("", Float, Bool)
+
+	env := SimpleEnv{
+		"runST":    NewScheme(TypeVarSet{'a'}, NewFnType(NewForallType(TypeVarSet{'s'}, st(s, a)), a)),
+		"newRef":   NewScheme(TypeVarSet{'s', 'a'}, NewFnType(a, st(s, ref(s, a)))),
+		"readRef":  NewScheme(TypeVarSet{'s', 'a'}, NewFnType(ref(s, a), st(s, a))),
+		"returnST": NewScheme(TypeVarSet{'s', 'a'}, NewFnType(a, st(s, a))),
+		"bindST":   NewScheme(TypeVarSet{'s', 'a', 'b'}, NewFnType(st(s, a), NewFnType(a, st(s, b)), st(s, b))),
+		"rank2":    NewScheme(nil, NewFnType(id, pair)),
+		"id":       NewScheme(TypeVarSet{'a'}, NewFnType(a, a)),
+		"plus":     NewScheme(nil, NewFnType(Float, Float, Float)),
+	}
+
+	// λf. (f 1, f true)
+	both := tuple{app{lit("f"), lit("1")}, app{lit("f"), lit("true")}}
+
+	higherRankTests := []struct {
+		name string
+		expr Expression
+
+		correct string
+		err     ErrorCode
+	}{
+		{"runST (returnST 1)", app{lit("runST"), app{lit("returnST"), lit("1")}}, "∀[]: Float", 0},
+		{"runST (bindST (newRef 1) readRef)", app{lit("runST"), app{app{lit("bindST"), app{lit("newRef"), lit("1")}}, lit("readRef")}}, "∀[]: Float", 0},
+		{"runST (newRef 1)", app{lit("runST"), app{lit("newRef"), lit("1")}}, "", CodeSkolemEscape},
+		{"λx. runST (returnST x)", λ{"x", app{lit("runST"), app{lit("returnST"), lit("x")}}}, "∀[a]: a → a", 0},
+
+		{"rank2 id", app{lit("rank2"), lit("id")}, "∀[]: (Float, Bool)", 0},
+		{"rank2 (λx. x)", app{lit("rank2"), λ{"x", lit("x")}}, "∀[]: (Float, Bool)", 0},
+		{"rank2 (plus 1)", app{lit("rank2"), app{lit("plus"), lit("1")}}, "", CodeRigidTypeVariable},
+		{"(rank2 (let z = λy. y in z), z)", tuple{app{lit("rank2"), let{"z", λ{"y", lit("y")}, lit("z")}}, lit("z")}, "", CodeUndefinedName},
+		{"λ(f : ∀a. a → a). (f 1, f true)", typedλ{"f", id, both}, "∀[]: (∀a. a → a) → (Float, Bool)", 0},
+		{"(λf. (f 1, f true) : (∀a. a → a) → (Float, Bool))", annotated{λ{"f", both}, NewScheme(nil, NewFnType(id, pair))}, "∀[]: (∀a. a → a) → (Float, Bool)", 0},
+		{"λf. (f 1, f true)", λ{"f", both}, "", CodeUnification},
+		{"(λ(f : ∀a. a → a). (f 1, f true)) id", app{typedλ{"f", id, both}, lit("id")}, "∀[]: (Float, Bool)", 0},
+		{"let g = λ(f : ∀a. a → a). f in g", let{"g", typedλ{"f", id, lit("f")}, lit("g")}, "∀[a]: (∀b. b → b) → a → a", 0},
+		{"λ(f : ∀a. a → b). f", typedλ{"f", NewForallType(TypeVarSet{'a'}, NewFnType(a, b)), lit("f")}, "∀[a, b]: (∀c. c → a) → b → a", 0},
+	}
+
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		opts = append(opts, WithBoolType(Bool))
+		for _, hts := range higherRankTests {
+			sch, err := Infer(env, hts.expr, opts...)
+			if hts.err != 0 {
+				var te TypeError
+				if !errors.As(err, &te) || te.Code() != hts.err {
+					t.Errorf("Test %q: Expected a %v error. Got %v, %v", hts.name, hts.err, sch, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("Test %q: %+v", hts.name, err)
+				continue
+			}
+			if got := fmt.Sprintf("%v", sch); got != hts.correct {
+				t.Errorf("Test %q: Expected %v. Got %v", hts.name, hts.correct, got)
+			}
+		}
+	}
+
+	// the expected type of Check can have polymorphic parameters too
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		opts = append(opts, WithBoolType(Bool))
+		if err := Check(env, λ{"f", both}, NewFnType(id, pair), opts...); err != nil {
+			t.Errorf("Check λf. (f 1, f true) : (∀a. a → a) → (Float, Bool): %v", err)
+		}
+	}
+}
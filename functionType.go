@@ -3,8 +3,12 @@ package hm
 import "fmt"
 
 // FunctionType is a type constructor that builds function types.
+//
+// A FunctionType may also have an effect row: the effects that the function performs when it is applied (see NewEffectRow).
+// A FunctionType without an effect row is total. It's the same as a FunctionType whose effect row is empty.
 type FunctionType struct {
 	a, b Type
+	eff  Type
 }
 
 // NewFnType creates a new FunctionType. Functions are by default right associative. This:
@@ -27,16 +31,59 @@ func NewFnType(ts ...Type) *FunctionType {
 	return retVal
 }
 
+// NewEffectfulFnType creates a new FunctionType with an effect row. Like NewFnType, it is right associative,
+// and the effects are performed when the last argument is applied. This:
+//		NewEffectfulFnType(io, a, a, a)
+// is a → a →{io} a, which is short hand for this:
+//		NewFnType(a, NewEffectfulFnType(io, a, a))
+func NewEffectfulFnType(eff Type, ts ...Type) *FunctionType {
+	retVal := NewFnType(ts...)
+	last := retVal
+	for i := 2; i < len(ts); i++ {
+		last = last.b.(*FunctionType)
+	}
+	last.eff = eff
+	return retVal
+}
+
 func (t *FunctionType) Name() string { return "→" }
 func (t *FunctionType) Apply(sub Subs) Substitutable {
 	t.a = t.a.Apply(sub).(Type)
 	t.b = t.b.Apply(sub).(Type)
+	if t.eff != nil {
+		t.eff = t.eff.Apply(sub).(Type)
+		if isEmptyRow(t.eff) {
+			t.eff = nil
+		}
+	}
 	return t
 }
 
-func (t *FunctionType) FreeTypeVar() TypeVarSet    { return t.a.FreeTypeVar().Union(t.b.FreeTypeVar()) }
-func (t *FunctionType) Format(s fmt.State, c rune) { fmt.Fprintf(s, "%v → %v", t.a, t.b) }
-func (t *FunctionType) String() string             { return fmt.Sprintf("%v", t) }
+func (t *FunctionType) FreeTypeVar() TypeVarSet {
+	ftv := t.a.FreeTypeVar().Union(t.b.FreeTypeVar())
+	if t.eff != nil {
+		ftv = t.eff.FreeTypeVar().Union(ftv)
+	}
+	return ftv
+}
+func (t *FunctionType) String() string { return fmt.Sprintf("%v", t) }
+
+// Format formats the function type. Functions are right associative, so an argument that is itself a function is parenthesized.
+// The effect row is written on the arrow: a →{io | e} b.
+func (t *FunctionType) Format(s fmt.State, c rune) {
+	switch t.a.(type) {
+	case *FunctionType, *ForallType, *RecursiveType:
+		fmt.Fprintf(s, "(%v) ", t.a)
+	default:
+		fmt.Fprintf(s, "%v ", t.a)
+	}
+	s.Write([]byte("→"))
+	if t.eff != nil {
+		formatEffects(s, t.eff)
+	}
+	fmt.Fprintf(s, " %v", t.b)
+}
+
 func (t *FunctionType) Normalize(k, v TypeVarSet) (Type, error) {
 	var a, b Type
 	var err error
@@ -48,17 +95,37 @@ func (t *FunctionType) Normalize(k, v TypeVarSet) (Type, error) {
 		return nil, err
 	}
 
-	return NewFnType(a, b), nil
+	retVal := NewFnType(a, b)
+	if t.eff != nil {
+		if retVal.eff, err = t.eff.Normalize(k, v); err != nil {
+			return nil, err
+		}
+	}
+	return retVal, nil
 }
+
+// Types returns the argument type and the return type, followed by the effect row if there is one.
 func (t *FunctionType) Types() Types {
-	retVal := BorrowTypes(2)
+	if t.eff == nil {
+		retVal := BorrowTypes(2)
+		retVal[0] = t.a
+		retVal[1] = t.b
+		return retVal
+	}
+	retVal := BorrowTypes(3)
 	retVal[0] = t.a
 	retVal[1] = t.b
+	retVal[2] = t.eff
 	return retVal
 }
 
 func (t *FunctionType) Eq(other Type) bool {
-	if ot, ok := other.(*FunctionType); ok {
+	if ot, ok := expandAlias(other).(*FunctionType); ok {
+		if t.eff != nil || ot.eff != nil {
+			if !effectRow(t.eff).Eq(effectRow(ot.eff)) {
+				return false
+			}
+		}
 		return ot.a.Eq(t.a) && ot.b.Eq(t.b)
 	}
 	return false
@@ -69,6 +136,9 @@ func (t *FunctionType) Eq(other Type) bool {
 // Arg returns the type of the function argument
 func (t *FunctionType) Arg() Type { return t.a }
 
+// Effects returns the effect row of the function. It is nil if the function is total.
+func (t *FunctionType) Effects() Type { return t.eff }
+
 // Ret returns the return type of a function. If recursive is true, it will get the final return type
 func (t *FunctionType) Ret(recursive bool) Type {
 	if !recursive {
@@ -120,5 +190,9 @@ func (t *FunctionType) Clone() interface{} {
 	} else {
 		retVal.b = t.b
 	}
+
+	if t.eff != nil {
+		retVal.eff = cloneType(t.eff)
+	}
 	return retVal
 }
@@ -12,36 +12,371 @@ type Fresher interface {
 	Fresh() TypeVariable
 }
 
-type inferer struct {
-	env Env
-	cs  Constraints
-	t   Type
+// typesFresher is the Fresher of the unifications that happen outside of the inference algorithm, as in Unify and Eq:
+// it generates the TypeVariables from NewTypeVar that are not in the types being unified.
+type typesFresher struct {
+	used TypeVarSet
+	next int
+}
+
+func newTypesFresher(ts ...Type) *typesFresher {
+	f := new(typesFresher)
+	for _, t := range ts {
+		f.used = typeVars(t, f.used)
+	}
+	return f
+}
+
+func (f *typesFresher) Fresh() TypeVariable {
+	for {
+		tv := NewTypeVar(f.next)
+		f.next++
+		if !f.used.Contains(tv) {
+			return tv
+		}
+	}
+}
 
-	count int
+// typeVars appends all the TypeVariables in t to tvs, including the ones that are bound in t and the ones that tell its Skolems apart
+func typeVars(t Type, tvs TypeVarSet) TypeVarSet {
+	switch tt := t.(type) {
+	case TypeVariable:
+		return append(tvs, tt)
+	case Skolem:
+		return append(tvs, tt.id)
+	case *ForallType:
+		tvs = append(tvs, tt.tvs...)
+	case *RecursiveType:
+		tvs = append(tvs, tt.tv)
+	}
+	for _, c := range t.Types() {
+		tvs = typeVars(c, tvs)
+	}
+	return tvs
+}
+
+type inferer struct {
+	env    Env
+	cs     Constraints
+	ps     Predicates // the predicates that have not been resolved yet
+	t      Type
+	sub    Subs // the solution of the constraints in cs[:solved]
+	solved int
+
+	origin *Origin // the origin of the constraints generated by the enclosing Expression
+	count  int
+	level  int    // the current let-depth
+	levels Levels // the level of each TypeVariable that has been generated
+
+	unionFind bool                  // use ufSolver instead of solver
+	recursive bool                  // cyclic types are RecursiveTypes instead of OccursCheckErrors
+	classes   *ClassEnv             // used to resolve the predicates
+	rankN     bool                  // a ForallType has been seen, so the types of functions are resolved eagerly
+	kinds     KindEnv               // the kinds of the type constructors. If it's nil, kinds are not checked
+	kinded    map[*Scheme]bool      // the Schemes in the Env whose kinds have been checked
+	lattice   *Lattice              // the subtype relation of the TypeConsts. If it's nil, there is no subtyping
+	subtypes  []SubtypeConstraint   // the subtype constraints that have not been solved yet
+	eff       Type                  // the effect row of the enclosing Lambda. If it's nil, effects are not inferred
+	expansive func(Expression) bool // the definitions that are not generalized. If it's nil, all of them are
+	typing    *Typing               // the types of the visited Expressions are recorded, if it's not nil
+
+	boolType     Type                 // the type of the conditions of If expressions
+	constructors map[string]*DataType // the DataType of each constructor
+	cases        []Case               // the patterns of the cases are checked after the constraints are solved
 }
 
 func newInferer(env Env) *inferer {
 	return &inferer{
-		env: env,
+		env:      env,
+		levels:   make(Levels),
+		boolType: TypeConst("Bool"),
+	}
+}
+
+// InferOpt is a functional option that configures the inference algorithm.
+type InferOpt func(*inferer)
+
+// WithUnionFind makes the inference algorithm solve its constraints with a union-find based unifier.
+// The results are the same, but it scales much better with the number of constraints.
+func WithUnionFind() InferOpt { return func(infer *inferer) { infer.unionFind = true } }
+
+// WithBoolType sets the type that the conditions of If expressions have to be. By default it's TypeConst("Bool").
+func WithBoolType(t Type) InferOpt { return func(infer *inferer) { infer.boolType = t } }
+
+// WithClasses makes the inference algorithm resolve the predicates of qualified types with the classes and instances of the ClassEnv.
+// Without a ClassEnv, any predicate on concrete types is an error.
+func WithClasses(ce *ClassEnv) InferOpt { return func(infer *inferer) { infer.classes = ce } }
+
+func (infer *inferer) classEnv() *ClassEnv {
+	if infer.classes == nil {
+		infer.classes = NewClassEnv()
+	}
+	return infer.classes
+}
+
+// solve solves the constraints, returning the resulting substitution
+func (infer *inferer) solve(cs Constraints) (Subs, error) {
+	if infer.unionFind {
+		s := newUFSolver()
+		s.fresher = solverFresher{infer}
+		s.recursive = infer.recursive
+		s.solve(cs)
+		return s.sub, s.err
+	}
+
+	// solver mutates the types in the constraints
+	s := newSolver()
+	s.fresher = solverFresher{infer}
+	s.solve(cs.Clone())
+	return s.sub, s.err
+}
+
+// solution returns the solution of all the constraints so far. Only the constraints added since the last solution are solved,
+// on top of the solution of the constraints before them. So every constraint is solved once, and the TypeVariables that are generated
+// while it's solved stay the same. The levels are adjusted with the new part of the solution only.
+func (infer *inferer) solution() (Subs, error) {
+	if infer.solved == len(infer.cs) {
+		return infer.sub, nil
 	}
+	sub, err := infer.solve(infer.cs[infer.solved:].Clone().Apply(infer.sub).(Constraints))
+	if err != nil {
+		return nil, err
+	}
+	infer.levels.Adjust(sub)
+	infer.sub, infer.solved = extend(infer.sub, sub), len(infer.cs)
+	return infer.sub, nil
 }
 
 func (infer *inferer) Fresh() TypeVariable {
-	retVal := letters[infer.count]
+	retVal := NewTypeVar(infer.count)
 	infer.count++
-	return TypeVariable(retVal)
+	infer.levels[retVal] = infer.level
+	return retVal
+}
+
+// solverFresher is the Fresher of the solvers. It shares the supply of TypeVariables of the inferer,
+// but the TypeVariables it generates (the tails of rows) are at no level until Levels.Adjust gives them one.
+type solverFresher struct{ infer *inferer }
+
+func (f solverFresher) Fresh() TypeVariable {
+	retVal := NewTypeVar(f.infer.count)
+	f.infer.count++
+	f.infer.levels[retVal] = noLevel
+	return retVal
 }
 
-func (infer *inferer) lookup(name string) error {
+// lookup instantiates the scheme of the name. The Expression is where the name occurs. It may be nil.
+func (infer *inferer) lookup(expr Expression, name string) error {
 	s, ok := infer.env.SchemeOf(name)
 	if !ok {
-		return errors.Errorf("Undefined %v", name)
+		return UndefinedNameError{name}
+	}
+	if err := infer.kindCheckScheme(s); err != nil {
+		return err
+	}
+	var ps Predicates
+	if infer.typing != nil && expr != nil {
+		rec := &instanceRecorder{Fresher: infer}
+		infer.t, ps = instantiate(rec, s)
+		infer.typing.Instantiations = append(infer.typing.Instantiations, Instantiation{Expr: expr, Name: name, Scheme: s, Types: rec.tvs})
+	} else {
+		infer.t, ps = instantiate(infer, s)
+	}
+	infer.noteForalls(infer.t)
+	infer.t = infer.openEffects(infer.instantiateForall(infer.t))
+	for _, p := range ps {
+		// the bounds of a scheme are subtype constraints on its instance
+		if p.Class == SubtypeClass && len(p.Types) == 2 && infer.lattice != nil {
+			infer.subtypes = append(infer.subtypes, SubtypeConstraint{p.Types[0], p.Types[1], infer.origin})
+			continue
+		}
+		infer.ps = append(infer.ps, p)
 	}
-	infer.t = Instantiate(infer, s)
 	return nil
 }
 
+// project returns the type of the projected element of a record of type t.
+//
+// The arity (or the labels) of the record has to be known, so the constraints are solved eagerly to find out what t is.
+func (infer *inferer) project(t Type, index int, label string, origin *Origin) (Type, error) {
+	sub, err := infer.solution()
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := expandAlias(cloneType(t).Apply(sub).(Type))
+	r, ok := resolved.(*Record)
+	if !ok {
+		return nil, ProjectionError{Type: resolved, Index: index, Label: label}
+	}
+	if label != "" {
+		if index = indexOf(r.labels, label); index < 0 {
+			return nil, ProjectionError{Type: resolved, Label: label}
+		}
+	}
+	if index < 0 || index >= len(r.ts) {
+		return nil, ProjectionError{Type: resolved, Index: index}
+	}
+
+	// t ~ (a, b, c ...) where the ith element is returned
+	ts := make([]Type, len(r.ts))
+	for i := range ts {
+		ts[i] = infer.Fresh()
+	}
+	infer.cs = append(infer.cs, Constraint{t, r.withTypes(ts), origin})
+	return ts[index], nil
+}
+
+// generalize generalizes the type of a definition over the type variables introduced deeper than the current level.
+// The predicates collected since start are reduced: those on the generalized type variables are moved into the scheme,
+// and the rest are deferred to the enclosing definition.
+func (infer *inferer) generalize(t Type, sub Subs, start int) (*Scheme, error) {
+	var bounds Predicates
+	if infer.lattice != nil {
+		var err error
+		if sub, err = infer.solveSubtypes(sub); err != nil {
+			return nil, err
+		}
+	}
+	t = cloneType(t).Apply(sub).(Type)
+	if infer.lattice != nil {
+		var err error
+		generalized := func(tv TypeVariable) bool { return infer.levels[tv] > infer.level }
+		if t, bounds, err = infer.simplifySubtypes(t, sub, generalized); err != nil {
+			return nil, err
+		}
+	}
+	sc := GeneralizeLevel(infer.level, infer.levels, t)
+	if len(infer.ps) == start {
+		sc.ps = bounds
+		return sc, nil
+	}
+
+	ps, err := infer.classEnv().Reduce(infer.ps[start:].Apply(sub).(Predicates))
+	if err != nil {
+		return nil, err
+	}
+
+	var deferred, retained Predicates
+	for _, p := range ps {
+		ftv := p.FreeTypeVar()
+		var generalized, ambiguous bool
+		for _, tv := range ftv {
+			if infer.levels[tv] <= infer.level {
+				continue
+			}
+			generalized = true
+			if !sc.tvs.Contains(tv) {
+				ambiguous = true
+			}
+		}
+		ReturnTypeVarSet(ftv)
+
+		switch {
+		case ambiguous:
+			return nil, AmbiguityError{Predicates{p}, t}
+		case generalized:
+			retained = append(retained, p)
+		default:
+			deferred = append(deferred, p)
+		}
+	}
+	infer.ps = append(infer.ps[:start], deferred...)
+	sc.ps = append(bounds, retained...)
+	return sc, nil
+}
+
+// bindingGroup infers the types of a strongly connected component of the bindings of a LetRecGroup, and adds their schemes to infer.env.
+// Within the component the bindings are monomorphic. They are generalized together once all of their definitions are inferred.
+func (infer *inferer) bindingGroup(group []Binding) (err error) {
+	parent := infer.origin
+	defer func() { infer.origin = parent }()
+
+	start := len(infer.ps)
+	infer.level++
+	tvs := make(Types, len(group))
+	for i, b := range group {
+		tvs[i] = infer.Fresh()
+		infer.env.Remove(b.Name())
+		infer.env.Add(b.Name(), &Scheme{t: tvs[i]})
+	}
+
+	for i, b := range group {
+		origin := &Origin{Expr: b, Reason: ReasonLetRec, Parent: parent}
+		infer.origin = origin
+		if err = infer.consGen(b.Body()); err != nil {
+			return errors.Wrapf(err, "Unable to infer the definition of %v in a letRec group. Def: %v", b.Name(), b.Body())
+		}
+		infer.cs = append(infer.cs, Constraint{tvs[i], infer.t, origin})
+	}
+	infer.level--
+
+	var sub Subs
+	if sub, err = infer.solution(); err != nil {
+		return errors.Wrapf(err, "Unable to solve the constraints of a letRec group")
+	}
+
+	// the bindings are generalized as one, so the predicates of the group are shared by all of them
+	defs := make([]Expression, len(group))
+	for i, b := range group {
+		defs[i] = b.Body()
+	}
+	infer.restrict(defs...)
+
+	var sc *Scheme
+	if sc, err = infer.generalize(NewRecordType("", tvs...), sub, start); err != nil {
+		return err
+	}
+	ts := sc.t.(*Record).ts
+	for i, b := range group {
+		ftv := ts[i].FreeTypeVar().Union(sc.ps.FreeTypeVar())
+		var quantified TypeVarSet
+		for _, tv := range sc.tvs {
+			if ftv.Contains(tv) {
+				quantified = append(quantified, tv)
+			}
+		}
+		bsc := &Scheme{tvs: quantified, ps: sc.ps, t: ts[i]}
+		infer.env.Remove(b.Name())
+		infer.env.Add(b.Name(), bsc)
+		infer.generalized(b, b.Name(), bsc)
+	}
+	return nil
+}
+
+// letDef infers the type of the definition of a let, and generalizes it
+func (infer *inferer) letDef(et Let) (*Scheme, error) {
+	parent := infer.origin
+	infer.origin = &Origin{Expr: et, Reason: ReasonLet, Parent: parent}
+	start := len(infer.ps)
+	infer.level++
+	if err := infer.consGen(et.Def()); err != nil {
+		return nil, errors.Wrapf(err, "Unable to infer the definition of a let %v. Def: %v", et, et.Def())
+	}
+	infer.level--
+	infer.origin = parent
+	defType, defCs := infer.t, infer.cs
+
+	sub, err := infer.solution()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to solve for the constraints of a def %v", defCs)
+	}
+	infer.restrict(et.Def())
+	sc, err := infer.generalize(defType, sub, start)
+	if err != nil {
+		return nil, err
+	}
+	infer.generalized(et, et.Name(), sc)
+	return sc, nil
+}
+
 func (infer *inferer) consGen(expr Expression) (err error) {
+	defer func() {
+		if err == nil {
+			infer.typed(expr, infer.t)
+		}
+		err = withPos(expr, err)
+	}()
 
 	// explicit types/inferers - can fail
 	switch et := expr.(type) {
@@ -61,69 +396,136 @@ func (infer *inferer) consGen(expr Expression) (err error) {
 
 	switch et := expr.(type) {
 	case Literal:
-		return infer.lookup(et.Name())
+		return infer.lookup(et, et.Name())
 
 	case Var:
-		if err = infer.lookup(et.Name()); err != nil {
+		if err = infer.lookup(et, et.Name()); err != nil {
 			infer.env.Add(et.Name(), &Scheme{t: et.Type()})
 			err = nil
 		}
 
 	case Lambda:
-		tv := infer.Fresh()
+		var param Type = infer.Fresh()
+		if tl, ok := et.(TypedLambda); ok && tl.ParamType() != nil {
+			// the TypeVariables of the parameter type are not rigid
+			pt := tl.ParamType()
+			infer.noteForalls(pt)
+			if err = infer.kindCheck(pt); err != nil {
+				return err
+			}
+			param, _ = instantiate(infer, &Scheme{tvs: pt.FreeTypeVar(), t: pt})
+		}
 		env := infer.env // backup
 
 		infer.env = infer.env.Clone()
 		infer.env.Remove(et.Name())
 		sc := new(Scheme)
-		sc.t = tv
+		sc.t = param
 		infer.env.Add(et.Name(), sc)
 
+		// the effects of the body are the effects of the function
+		eff := infer.eff
+		if eff != nil {
+			infer.eff = infer.Fresh()
+		}
 		if err = infer.consGen(et.Body()); err != nil {
 			return errors.Wrapf(err, "Unable to infer body of %v. Body: %v", et, et.Body())
 		}
 
-		infer.t = NewFnType(tv, infer.t)
+		infer.t = infer.fnType(param, infer.t)
 		infer.env = env // restore backup
+		infer.eff = eff
 
 	case Apply:
+		origin := &Origin{Expr: et, Reason: ReasonApply, Parent: infer.origin}
+		infer.origin = origin
+		defer func() { infer.origin = origin.Parent }()
+
 		if err = infer.consGen(et.Fn()); err != nil {
 			return errors.Wrapf(err, "Unable to infer Fn of Apply: %v. Fn: %v", et, et.Fn())
 		}
-		fnType, fnCs := infer.t, infer.cs
+		fnType := infer.t
+
+		// an argument for a polymorphic parameter is checked against the parameter type
+		if infer.rankN {
+			if ft, ok := infer.resolve(fnType).(*FunctionType); ok {
+				if param, ok := ft.Arg().(*ForallType); ok {
+					if err = infer.check(et.Body(), param); err != nil {
+						return errors.Wrapf(err, "Unable to check body of Apply: %v. Body: %v", et, et.Body())
+					}
+					infer.perform(ft, origin)
+					infer.t = infer.instantiateForall(ft.Ret(false))
+					return nil
+				}
+			}
+		}
 
 		if err = infer.consGen(et.Body()); err != nil {
 			return errors.Wrapf(err, "Unable to infer body of Apply: %v. Body: %v", et, et.Body())
 		}
 		bodyType, bodyCs := infer.t, infer.cs
 
+		// the constraints of the body already include the constraints of fn
 		tv := infer.Fresh()
-		cs := append(fnCs, bodyCs...)
-		cs = append(cs, Constraint{fnType, NewFnType(bodyType, tv)})
+		if infer.lattice != nil {
+			// with subtyping, the argument only has to be a subtype of the parameter
+			param := infer.Fresh()
+			infer.cs = append(bodyCs, Constraint{fnType, infer.fnType(param, tv), origin})
+			infer.subtypes = append(infer.subtypes, SubtypeConstraint{bodyType, param, origin})
+			infer.t = tv
+			return nil
+		}
+		cs := append(bodyCs, Constraint{fnType, infer.fnType(bodyType, tv), origin})
 
 		infer.t = tv
 		infer.cs = cs
 
+	case LetRecGroup:
+		env := infer.env // backup
+		infer.env = infer.env.Clone()
+
+		// the components are in dependency order, so each of them sees the generalized schemes of the ones before it
+		for _, group := range bindingGroups(et.Bindings()) {
+			if err = infer.bindingGroup(group); err != nil {
+				return err
+			}
+		}
+
+		if err = infer.consGen(et.Body()); err != nil {
+			return errors.Wrapf(err, "Unable to infer body of letRec group %v. Body: %v", et, et.Body())
+		}
+		infer.env = env // restore backup
+
 	case LetRec:
 		tv := infer.Fresh()
-		// env := infer.env // backup
+		env := infer.env // backup
 
 		infer.env = infer.env.Clone()
 		infer.env.Remove(et.Name())
 		infer.env.Add(et.Name(), &Scheme{tvs: TypeVarSet{tv}, t: tv})
 
+		parent := infer.origin
+		infer.origin = &Origin{Expr: et, Reason: ReasonLetRec, Parent: parent}
+		start := len(infer.ps)
+		infer.level++
 		if err = infer.consGen(et.Def()); err != nil {
 			return errors.Wrapf(err, "Unable to infer the definition of a letRec %v. Def: %v", et, et.Def())
 		}
+		infer.level--
+		infer.origin = parent
 		defType, defCs := infer.t, infer.cs
 
-		s := newSolver()
-		s.solve(defCs)
-		if s.err != nil {
-			return errors.Wrapf(s.err, "Unable to solve constraints of def: %v", defCs)
+		var sub Subs
+		if sub, err = infer.solution(); err != nil {
+			return errors.Wrapf(err, "Unable to solve constraints of def: %v", defCs)
 		}
 
-		sc := Generalize(infer.env.Apply(s.sub).(Env), defType.Apply(s.sub).(Type))
+		var sc *Scheme
+		infer.restrict(et.Def())
+		if sc, err = infer.generalize(defType, sub, start); err != nil {
+			return err
+		}
+		infer.generalized(et, et.Name(), sc)
 
 		infer.env.Remove(et.Name())
 		infer.env.Add(et.Name(), sc)
@@ -131,26 +533,17 @@ func (infer *inferer) consGen(expr Expression) (err error) {
 		if err = infer.consGen(et.Body()); err != nil {
 			return errors.Wrapf(err, "Unable to infer body of letRec %v. Body: %v", et, et.Body())
 		}
+		infer.env = env // restore backup
 
-		infer.t = infer.t.Apply(s.sub).(Type)
-		infer.cs = infer.cs.Apply(s.sub).(Constraints)
-		infer.cs = append(infer.cs, defCs...)
+		// the constraints of the body already include defCs. They are left unsolved,
+		// as the types in the env have not been substituted.
 
 	case Let:
-		env := infer.env
-
-		if err = infer.consGen(et.Def()); err != nil {
-			return errors.Wrapf(err, "Unable to infer the definition of a let %v. Def: %v", et, et.Def())
+		var sc *Scheme
+		if sc, err = infer.letDef(et); err != nil {
+			return err
 		}
-		defType, defCs := infer.t, infer.cs
-
-		s := newSolver()
-		s.solve(defCs)
-		if s.err != nil {
-			return errors.Wrapf(s.err, "Unable to solve for the constraints of a def %v", defCs)
-		}
-
-		sc := Generalize(env.Apply(s.sub).(Env), defType.Apply(s.sub).(Type))
+		env := infer.env // backup
 		infer.env = infer.env.Clone()
 		infer.env.Remove(et.Name())
 		infer.env.Add(et.Name(), sc)
@@ -158,13 +551,162 @@ func (infer *inferer) consGen(expr Expression) (err error) {
 		if err = infer.consGen(et.Body()); err != nil {
 			return errors.Wrapf(err, "Unable to infer body of let %v. Body: %v", et, et.Body())
 		}
+		infer.env = env // restore backup
+
+		// the constraints of the body already include defCs. They are left unsolved,
+		// as the types in the env have not been substituted.
+
+	case Annotated:
+		// the annotated expression is checked against the annotation, with Skolems for its quantified TypeVariables
+		annotation := closeScheme(et.Annotation())
+		infer.noteForalls(annotation.t)
+		if err = infer.kindCheck(annotation.t); err != nil {
+			return err
+		}
+
+		origin := &Origin{Expr: et, Reason: ReasonAnnotation, Parent: infer.origin}
+		infer.origin = origin
+		start := len(infer.ps)
+		infer.level++
+		skolemized, given, sks := infer.skolemize(annotation)
+		if err = infer.check(et.Body(), skolemized); err != nil {
+			return errors.Wrapf(err, "Unable to check the annotated expression %v", et.Body())
+		}
+		infer.level--
+		infer.origin = origin.Parent
+
+		if err = infer.checkSkolems(sks, given, start); err != nil {
+			return err
+		}
+		var ps Predicates
+		infer.t, ps = instantiate(infer, annotation)
+		infer.t = infer.instantiateForall(infer.t)
+		infer.ps = append(infer.ps, ps...)
+
+	case Extend:
+		origin := &Origin{Expr: et, Reason: ReasonExtend, Parent: infer.origin}
+		infer.origin = origin
+		defer func() { infer.origin = origin.Parent }()
+
+		if err = infer.consGen(et.Body()); err != nil {
+			return errors.Wrapf(err, "Unable to infer the record of %v. Record: %v", et, et.Body())
+		}
+		recType := infer.t
+
+		if err = infer.consGen(et.Value()); err != nil {
+			return errors.Wrapf(err, "Unable to infer the value of %v. Value: %v", et, et.Value())
+		}
+
+		// {label: value | ρ}, where the record is {| ρ}
+		tail := infer.Fresh()
+		infer.cs = append(infer.cs, Constraint{recType, NewRow(tail), origin})
+		infer.t = NewRow(tail, Field{et.Label(), infer.t})
+
+	case Select:
+		origin := &Origin{Expr: et, Reason: ReasonSelect, Parent: infer.origin}
+		infer.origin = origin
+		defer func() { infer.origin = origin.Parent }()
+
+		if err = infer.consGen(et.Body()); err != nil {
+			return errors.Wrapf(err, "Unable to infer the record of %v. Record: %v", et, et.Body())
+		}
+
+		// a, where the record is {label: a | ρ}
+		fieldType, tail := infer.Fresh(), infer.Fresh()
+		infer.cs = append(infer.cs, Constraint{infer.t, NewRow(tail, Field{et.Label(), fieldType}), origin})
+		infer.t = fieldType
+
+	case Restrict:
+		origin := &Origin{Expr: et, Reason: ReasonRestrict, Parent: infer.origin}
+		infer.origin = origin
+		defer func() { infer.origin = origin.Parent }()
+
+		if err = infer.consGen(et.Body()); err != nil {
+			return errors.Wrapf(err, "Unable to infer the record of %v. Record: %v", et, et.Body())
+		}
+
+		// {| ρ}, where the record is {label: a | ρ}
+		fieldType, tail := infer.Fresh(), infer.Fresh()
+		infer.cs = append(infer.cs, Constraint{infer.t, NewRow(tail, Field{et.Label(), fieldType}), origin})
+		infer.t = NewRow(tail)
+
+	case If:
+		parent := infer.origin
+		condOrigin := &Origin{Expr: et, Reason: ReasonIfCondition, Parent: parent}
+		infer.origin = condOrigin
+		if err = infer.consGen(et.Cond()); err != nil {
+			return errors.Wrapf(err, "Unable to infer the condition of %v. Condition: %v", et, et.Cond())
+		}
+		infer.cs = append(infer.cs, Constraint{infer.t, infer.boolType, condOrigin})
+
+		branchOrigin := &Origin{Expr: et, Reason: ReasonIfBranches, Parent: parent}
+		infer.origin = branchOrigin
+		if err = infer.consGen(et.Then()); err != nil {
+			return errors.Wrapf(err, "Unable to infer the then branch of %v. Then: %v", et, et.Then())
+		}
+		thenType := infer.t
+
+		if err = infer.consGen(et.Else()); err != nil {
+			return errors.Wrapf(err, "Unable to infer the else branch of %v. Else: %v", et, et.Else())
+		}
+		infer.origin = parent
+		infer.cs = append(infer.cs, Constraint{thenType, infer.t, branchOrigin})
+		infer.t = thenType
+
+	case Tuple:
+		elems := et.Elems()
+		ts := make([]Type, len(elems))
+		for i, e := range elems {
+			if err = infer.consGen(e); err != nil {
+				return errors.Wrapf(err, "Unable to infer element %d of %v. Element: %v", i, et, e)
+			}
+			ts[i] = infer.t
+		}
+		infer.t = NewRecordType("", ts...)
+
+	case Project:
+		origin := &Origin{Expr: et, Reason: ReasonProject, Parent: infer.origin}
+		if err = infer.consGen(et.Body()); err != nil {
+			return errors.Wrapf(err, "Unable to infer the record of %v. Record: %v", et, et.Body())
+		}
+		if infer.t, err = infer.project(infer.t, et.Index(), et.Label(), origin); err != nil {
+			return err
+		}
+
+	case Case:
+		if err = infer.consGen(et.Body()); err != nil {
+			return errors.Wrapf(err, "Unable to infer the scrutinee of %v. Scrutinee: %v", et, et.Body())
+		}
+		scrutinee := infer.t
+
+		retType := infer.Fresh()
+		env := infer.env // backup
+		parent := infer.origin
+		alts := et.Alternatives()
+		for _, alt := range alts {
+			infer.env = env.Clone()
+
+			var pt Type
+			patOrigin := &Origin{Expr: alt, Reason: ReasonPattern, Parent: parent}
+			if pt, err = infer.patternType(alt.Pattern(), patOrigin); err != nil {
+				return errors.Wrapf(err, "Unable to infer the pattern of %v", alt)
+			}
+			infer.cs = append(infer.cs, Constraint{scrutinee, pt, patOrigin})
 
-		infer.t = infer.t.Apply(s.sub).(Type)
-		infer.cs = infer.cs.Apply(s.sub).(Constraints)
-		infer.cs = append(infer.cs, defCs...)
+			origin := &Origin{Expr: alt, Reason: ReasonCase, Parent: parent}
+			infer.origin = origin
+			if err = infer.consGen(alt.Body()); err != nil {
+				return errors.Wrapf(err, "Unable to infer the alternative %v. Body: %v", alt, alt.Body())
+			}
+			infer.origin = parent
+			infer.cs = append(infer.cs, Constraint{retType, infer.t, origin})
+		}
+		infer.env = env // restore backup
+		infer.cases = append(infer.cases, et)
+		infer.t = retType
 
 	default:
-		return errors.Errorf("Expression of %T is unhandled", expr)
+		return UnhandledExpressionError{expr}
 	}
 
 	return nil
@@ -178,6 +720,12 @@ func (infer *inferer) consGen(expr Expression) (err error) {
 //		       Γ ⊢ e: T
 //
 func Instantiate(f Fresher, s *Scheme) Type {
+	t, _ := instantiate(f, s)
+	return t
+}
+
+// instantiate instantiates the scheme, returning the type as well as the instantiated predicates of the scheme.
+func instantiate(f Fresher, s *Scheme) (Type, Predicates) {
 	l := len(s.tvs)
 	tvs := make(TypeVarSet, l)
 
@@ -194,7 +742,13 @@ func Instantiate(f Fresher, s *Scheme) Type {
 		sub = sub.Add(tv, fr)
 	}
 
-	return s.t.Apply(sub).(Type)
+	// Apply may mutate the type in place, so the type of the scheme has to be cloned,
+	// otherwise every instance of the scheme will share the same type.
+	t := s.t
+	if l > 0 {
+		t = cloneType(t)
+	}
+	return t.Apply(sub).(Type), s.ps.Apply(sub).(Predicates)
 }
 
 // Generalize takes an env and a type and creates the most general possible type - which is a polytype
@@ -278,7 +832,7 @@ ret:
 //		--------------------------------
 //		     Γ ⊢ let x = e1 in e2: T2
 //
-func Infer(env Env, expr Expression) (*Scheme, error) {
+func Infer(env Env, expr Expression, opts ...InferOpt) (*Scheme, error) {
 	if expr == nil {
 		return nil, errors.Errorf("Cannot infer a nil expression")
 	}
@@ -288,23 +842,69 @@ func Infer(env Env, expr Expression) (*Scheme, error) {
 	}
 
 	infer := newInferer(env)
-	if err := infer.consGen(expr); err != nil {
+	for _, opt := range opts {
+		opt(infer)
+	}
+	if err := infer.kindCheckDataTypes(); err != nil {
 		return nil, err
 	}
+	t, ps, _, err := infer.top(expr, nil)
+	if err != nil {
+		return nil, err
+	}
+	return closeOver(ps, t, infer.expansive == nil || !infer.expansive(expr))
+}
 
-	s := newSolver()
-	s.solve(infer.cs)
+// top infers the type of the whole expression, or checks it against the expected type if that isn't nil.
+// The type and its predicates are returned with the solution of the constraints applied, along with the solution itself.
+func (infer *inferer) top(expr Expression, expected Type) (t Type, ps Predicates, sub Subs, err error) {
+	if expected == nil {
+		err = infer.consGen(expr)
+	} else {
+		err = infer.check(expr, expected)
+	}
+	if err != nil {
+		return
+	}
 
-	if s.err != nil {
-		return nil, s.err
+	if sub, err = infer.solution(); err != nil {
+		return
+	}
+	if infer.lattice != nil {
+		if sub, err = infer.solveSubtypes(sub); err != nil {
+			return
+		}
+	}
+
+	// type errors in patterns take precedence over the exhaustiveness of patterns
+	for _, c := range infer.cases {
+		if err = infer.checkPatterns(c.Alternatives()); err != nil {
+			return nil, nil, nil, withPos(c, err)
+		}
 	}
 
 	if infer.t == nil {
-		return nil, errors.Errorf("infer.t is nil")
+		return nil, nil, nil, errors.Errorf("infer.t is nil")
 	}
 
-	t := infer.t.Apply(s.sub).(Type)
-	return closeOver(t)
+	t = infer.t.Apply(sub).(Type)
+	if len(infer.ps) > 0 {
+		if ps, err = infer.classEnv().Reduce(infer.ps.Apply(sub).(Predicates)); err != nil {
+			return
+		}
+	}
+	if infer.lattice != nil {
+		var bounds Predicates
+		all := func(TypeVariable) bool { return true }
+		if t, bounds, err = infer.simplifySubtypes(t, sub, all); err != nil {
+			return
+		}
+		ps = append(bounds, ps...)
+	}
+	if infer.typing != nil {
+		infer.typing.apply(sub)
+	}
+	return
 }
 
 // Unify unifies the two types and returns a list of substitutions.
@@ -327,7 +927,13 @@ func Infer(env Env, expr Expression) (*Scheme, error) {
 //		---------------
 //		 a ~ T : [a/T]
 //
+// Unifying rows may need fresh TypeVariables for their tails. They are the first TypeVariables from NewTypeVar that are not in a or b.
 func Unify(a, b Type) (sub Subs, err error) {
+	return unify(newTypesFresher(a, b), a, b)
+}
+
+// unify is Unify, with the fresh TypeVariables from the Fresher.
+func unify(f Fresher, a, b Type) (sub Subs, err error) {
 	logf("%v ~ %v", a, b)
 	enterLoggingContext()
 	defer leaveLoggingContext()
@@ -343,8 +949,47 @@ func Unify(a, b Type) (sub Subs, err error) {
 		if btv, ok := b.(TypeVariable); ok {
 			return bind(btv, a)
 		}
-		atypes := a.Types()
-		btypes := b.Types()
+		// aliases are only expanded when they have to be
+		if ea, eb, ok := expandAliases(a, b); ok {
+			return unify(f, ea, eb)
+		}
+
+		_, aIsRecursive := a.(*RecursiveType)
+		_, bIsRecursive := b.(*RecursiveType)
+		if aIsRecursive || bIsRecursive {
+			return unifyRecursive(f, a, b)
+		}
+
+		if err = rigid(a, b); err != nil {
+			return nil, err
+		}
+		af, aIsForall := a.(*ForallType)
+		bf, bIsForall := b.(*ForallType)
+		switch {
+		case aIsForall && bIsForall:
+			return unifyForalls(f, af, bf)
+		case aIsForall || bIsForall:
+			return nil, UnificationError{a, b}
+		}
+
+		ar, aIsRow := a.(*Row)
+		br, bIsRow := b.(*Row)
+		switch {
+		case aIsRow && bIsRow:
+			var as, bs Types
+			if as, bs, err = unifyRows(f, ar, br); err != nil {
+				return nil, err
+			}
+			return unifyMany(f, as, bs)
+		case aIsRow || bIsRow:
+			return nil, UnificationError{a, b}
+		}
+
+		if !sameConstructor(a, b) {
+			return nil, UnificationError{a, b}
+		}
+
+		atypes, btypes := componentTypes(a, b)
 		defer ReturnTypes(atypes)
 		defer ReturnTypes(btypes)
 
@@ -352,21 +997,22 @@ func Unify(a, b Type) (sub Subs, err error) {
 			goto e
 		}
 
-		return unifyMany(atypes, btypes)
+		return unifyMany(f, atypes, btypes)
 
 	e:
 	}
-	err = errors.Errorf("Unification Fail: %v ~ %v cannot be unified", a, b)
+	err = UnificationError{a, b}
 	return
 }
 
-func unifyMany(a, b Types) (sub Subs, err error) {
+func unifyMany(f Fresher, a, b Types) (sub Subs, err error) {
 	logf("UnifyMany %v %v", a, b)
 	enterLoggingContext()
 	defer leaveLoggingContext()
 
 	if len(a) != len(b) {
-		return nil, errors.Errorf("Unequal length. a: %v b %v", a, b)
+		// a and b may be borrowed from the pool, so the error has to keep its own copies
+		return nil, ArityMismatchError{append(Types(nil), a...), append(Types(nil), b...)}
 	}
 
 	for i, at := range a {
@@ -377,15 +1023,21 @@ func unifyMany(a, b Types) (sub Subs, err error) {
 			bt = bt.Apply(sub).(Type)
 		}
 
+		// identical types need no substitution.
+		if at.Eq(bt) {
+			continue
+		}
+
 		var s2 Subs
-		if s2, err = Unify(at, bt); err != nil {
+		if s2, err = unify(f, at, bt); err != nil {
 			return nil, err
 		}
 
 		if sub == nil {
 			sub = s2
 		} else {
-			sub2 := compose(sub, s2)
+			// s2 comes after sub, so it has to be applied to sub
+			sub2 := compose(s2, sub)
 			defer ReturnSubs(s2)
 			if sub2 != sub {
 				defer ReturnSubs(sub)
@@ -398,10 +1050,11 @@ func unifyMany(a, b Types) (sub Subs, err error) {
 
 func bind(tv TypeVariable, t Type) (sub Subs, err error) {
 	logf("Binding %v to %v", tv, t)
+	t = unaliasOccurring(tv, t)
 	switch {
 	// case tv == t:
 	case occurs(tv, t):
-		err = errors.Errorf("recursive unification")
+		err = OccursCheckError{tv, t}
 	default:
 		ssub := BorrowSSubs(1)
 		ssub.s[0] = Substitution{tv, t}
@@ -418,9 +1071,25 @@ func occurs(tv TypeVariable, s Substitutable) bool {
 	return ftv.Contains(tv)
 }
 
-func closeOver(t Type) (sch *Scheme, err error) {
+// closeOver creates the scheme of the inferred type. If generalize is false (the expression is expansive),
+// the TypeVariables of the scheme are named like the quantified ones would be, but they are left free.
+func closeOver(ps Predicates, t Type, generalize bool) (sch *Scheme, err error) {
 	sch = Generalize(nil, t)
+	if len(ps) > 0 {
+		// all the type variables in the predicates have to be determined by the type
+		for _, p := range ps {
+			for _, tv := range p.FreeTypeVar() {
+				if !sch.tvs.Contains(tv) {
+					return nil, AmbiguityError{Predicates{p}, t}
+				}
+			}
+		}
+		sch.ps = ps
+	}
 	err = sch.Normalize()
+	if !generalize {
+		sch.tvs = nil
+	}
 	logf("closeoversch: %v", sch)
 	return
 }
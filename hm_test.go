@@ -1,6 +1,9 @@
 package hm
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+)
 
 var unifyTests = []struct {
 	name string
@@ -166,3 +169,46 @@ func TestInfer(t *testing.T) {
 	}
 
 }
+
+func TestInfer_ManyTypeVariables(t *testing.T) {
+	// λx0. λx1. ... λx99. 1
+	var expr Expression = lit("1")
+	for i := 99; i >= 0; i-- {
+		expr = λ{fmt.Sprintf("x%d", i), expr}
+	}
+
+	sch, err := Infer(nil, expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sch.tvs) != 100 {
+		t.Fatalf("Expected 100 type variables in the scheme. Got %d instead", len(sch.tvs))
+	}
+
+	ts := sch.t.(*FunctionType).FlatTypes()
+	for i, tv := range ts[:100] {
+		if tv != NewTypeVar(i) {
+			t.Errorf("Expected argument %d to have type %v. Got %v instead", i, NewTypeVar(i), tv)
+			break
+		}
+	}
+	if ts[100] != Float {
+		t.Errorf("Expected the return type to be Float. Got %v instead", ts[100])
+	}
+}
+
+func TestInstantiate(t *testing.T) {
+	s := NewScheme(TypeVarSet{'a'}, NewFnType(TypeVariable('a'), TypeVariable('a')))
+	infer := newInferer(nil)
+
+	t0 := Instantiate(infer, s)
+	t1 := Instantiate(infer, s)
+	if t0.Eq(t1) {
+		t.Errorf("Expected each instance of a scheme to have fresh type variables. Got %v and %v", t0, t1)
+	}
+
+	if !s.t.Eq(NewFnType(TypeVariable('a'), TypeVariable('a'))) {
+		t.Errorf("Instantiate should not mutate the scheme. Got %v", s)
+	}
+}
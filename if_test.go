@@ -0,0 +1,86 @@
+package hm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestInfer_If(t *testing.T) {
+	a := TypeVariable('a')
+	env := SimpleEnv{
+		"isZero": NewScheme(nil, NewFnType(Float, Bool)),
+		"id":     NewScheme(TypeVarSet{'a'}, NewFnType(a, a)),
+	}
+
+	ifTests := []struct {
+		name string
+		expr Expression
+
+		correct string
+		err     ErrorCode
+	}{
+		{"if true then 1 else 2", ifExpr{lit("true"), lit("1"), lit("2")}, "∀[]: Float", 0},
+		{"λn. if isZero n then n else 1", λ{"n", ifExpr{app{lit("isZero"), lit("n")}, lit("n"), lit("1")}}, "∀[]: Float → Float", 0},
+		{"λc. λx. if c then x else id x", λ{"c", λ{"x", ifExpr{lit("c"), lit("x"), app{lit("id"), lit("x")}}}}, "∀[a]: Bool → a → a", 0},
+		{"if 1 then 1 else 2", ifExpr{lit("1"), lit("1"), lit("2")}, "", CodeUnification},
+		{"if true then 1 else false", ifExpr{lit("true"), lit("1"), lit("false")}, "", CodeBranchMismatch},
+		{"λx. if true then x else 1", λ{"x", ifExpr{lit("true"), lit("x"), lit("1")}}, "∀[]: Float → Float", 0},
+	}
+
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		opts = append(opts, WithBoolType(Bool))
+		for _, its := range ifTests {
+			sch, err := Infer(env, its.expr, opts...)
+			if its.err != 0 {
+				var te TypeError
+				if !errors.As(err, &te) || te.Code() != its.err {
+					t.Errorf("Test %q: Expected a %v error. Got %v, %v", its.name, its.err, sch, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("Test %q: %+v", its.name, err)
+				continue
+			}
+			if got := fmt.Sprintf("%v", sch); got != its.correct {
+				t.Errorf("Test %q: Expected %v. Got %v", its.name, its.correct, got)
+			}
+		}
+	}
+}
+
+func TestInfer_IfErrors(t *testing.T) {
+	env := SimpleEnv{"isZero": NewScheme(nil, NewFnType(Float, Bool))}
+
+	// the branches are reported with the solution applied
+	expr := λ{"n", ifExpr{app{lit("isZero"), lit("n")}, lit("n"), lit("true")}}
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		_, err := Infer(env, expr, append(opts, WithBoolType(Bool))...)
+		var bme *BranchMismatchError
+		if !errors.As(err, &bme) {
+			t.Fatalf("Expected a *BranchMismatchError. Got %v", err)
+		}
+		if bme.Then != Float || bme.Else != Bool {
+			t.Errorf("Expected the branches to be Float and Bool. Got %v and %v", bme.Then, bme.Else)
+		}
+		if !strings.Contains(err.Error(), "in branches of if") {
+			t.Errorf("Expected the origin in the error. Got %v", err)
+		}
+		var ue UnificationError
+		if !errors.As(err, &ue) {
+			t.Errorf("Expected the underlying UnificationError. Got %v", err)
+		}
+	}
+
+	// by default, conditions are TypeConst("Bool")
+	if _, err := Infer(env, ifExpr{lit("true"), lit("1"), lit("2")}); err == nil {
+		t.Errorf("Expected an error, as the condition is not TypeConst(\"Bool\")")
+	}
+	env["b"] = NewScheme(nil, TypeConst("Bool"))
+	if _, err := Infer(env, ifExpr{lit("b"), lit("1"), lit("2")}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,432 @@
+package hm
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"io"
+	"math"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// Hasher is an Expression that knows the hash of its contents. Two Expressions with the same hash are assumed to be the same,
+// so an Incremental checker doesn't infer a definition again if it is rebuilt with the same contents.
+//
+// The Expressions that are not Hashers are hashed structurally, by the values of their fields. A Hasher may be cheaper,
+// or it may leave out what doesn't matter to the types, such as the positions of the nodes.
+type Hasher interface {
+	Hash() uint64
+}
+
+// Incremental infers the types of a program: a list of top-level bindings, each of which may refer to itself and to the bindings before it.
+// It caches the scheme of every binding, so when the program is edited, only the bindings whose definitions changed,
+// and the bindings that depend on them, are inferred again.
+//
+// A definition is unchanged if it has the same hash as before. The hash of a Hasher is its Hash, and the hash of any other Expression
+// is computed from its contents: the values of its fields, through pointers, interfaces and slices. So the definitions that are rebuilt
+// with the same contents are reused, and the ones that are edited in place are inferred again.
+// A binding depends on the names that it refers to. It is inferred again if any of their schemes is different.
+//
+// Every binding is generalized like the expression given to Infer is. With WithValueRestriction, the TypeVariables of the expansive bindings
+// are not generalized: they are monomorphic, so they are determined by the uses of the bindings in the bindings after them.
+type Incremental struct {
+	env   Env
+	opts  []InferOpt
+	cache map[string]*cachedBinding
+	count int  // the number of TypeVariables that have been used. The monomorphic TypeVariables are among them
+	mono  Subs // the types of the monomorphic TypeVariables that have been determined by the bindings, as of the last Update
+}
+
+// cachedBinding is the result of the inference of a binding.
+type cachedBinding struct {
+	hash   uint64 // the hash of the definition
+	scheme *Scheme
+	typing *Typing
+	deps   map[string]*Scheme // the schemes of the names that the definition refers to, when it was inferred
+	mono   Subs               // the types of the monomorphic TypeVariables of the env that the definition determined
+}
+
+// Changes reports what an Update did to the schemes of the bindings.
+type Changes struct {
+	Changed    []string // the bindings whose schemes are different from before, including the new bindings and the ones whose monomorphic TypeVariables are determined differently
+	Removed    []string // the bindings that are no longer in the program
+	Reinferred []string // the bindings that were inferred again: the edited ones and the ones that depend on them
+}
+
+// NewIncremental creates a new Incremental checker. The bindings of the program may refer to the names in the env.
+// The options are used to infer every binding.
+func NewIncremental(env Env, opts ...InferOpt) *Incremental {
+	if env == nil {
+		env = make(SimpleEnv)
+	}
+	return &Incremental{
+		env:   env,
+		opts:  opts,
+		cache: make(map[string]*cachedBinding),
+	}
+}
+
+// Update infers the types of the bindings of the edited program, reusing the schemes of the bindings that have not changed.
+//
+// If a binding can't be inferred, the error is returned along with the changes to the bindings before it.
+// The bindings after it are left as they were, and they are checked again by the next Update.
+func (inc *Incremental) Update(bindings []Binding) (*Changes, error) {
+	changes := new(Changes)
+	prev := make(map[string]*Scheme, len(inc.cache))
+	for name, c := range inc.cache {
+		prev[name] = inc.resolve(c.scheme)
+	}
+	var bound []string
+	defer func() { changes.Changed = inc.changed(prev, bound) }()
+
+	env := inc.env.Clone()
+	inc.mono = nil
+	seen := make(map[string]bool, len(bindings))
+	for _, b := range bindings {
+		name := b.Name()
+		if seen[name] {
+			return changes, errors.Errorf("%v is bound more than once", name)
+		}
+		seen[name] = true
+
+		cached, ok := inc.cache[name]
+		if ok && cached.valid(b.Body(), env) {
+			env = inc.bind(env, seen, name, cached)
+			bound = append(bound, name)
+			continue
+		}
+
+		changes.Reinferred = append(changes.Reinferred, name)
+		c, err := inc.infer(env, b)
+		if err != nil {
+			delete(inc.cache, name)
+			return changes, errors.Wrapf(err, "Unable to infer the binding %v", name)
+		}
+		inc.cache[name] = c
+		env = inc.bind(env, seen, name, c)
+		bound = append(bound, name)
+	}
+
+	for name := range inc.cache {
+		if !seen[name] {
+			changes.Removed = append(changes.Removed, name)
+			delete(inc.cache, name)
+		}
+	}
+	return changes, nil
+}
+
+// SchemeOf returns the scheme of the binding, as of the last Update.
+func (inc *Incremental) SchemeOf(name string) (*Scheme, bool) {
+	c, ok := inc.cache[name]
+	if !ok {
+		return nil, false
+	}
+	return inc.resolve(c.scheme), true
+}
+
+// TypingOf returns the types of the sub-expressions of the definition of the binding, as of the last time it was inferred.
+func (inc *Incremental) TypingOf(name string) (*Typing, bool) {
+	c, ok := inc.cache[name]
+	if !ok {
+		return nil, false
+	}
+	return c.typing, true
+}
+
+// changed returns the bound names whose schemes, with their monomorphic TypeVariables substituted, are different from the previous ones.
+func (inc *Incremental) changed(prev map[string]*Scheme, bound []string) (changed []string) {
+	for _, name := range bound {
+		s, ok := prev[name]
+		if !ok || !sameScheme(s, inc.resolve(inc.cache[name].scheme)) {
+			changed = append(changed, name)
+		}
+	}
+	return
+}
+
+// bind adds the scheme of the binding to the env. The monomorphic TypeVariables that the binding determined are substituted
+// in the schemes of the bindings that are already in the env.
+func (inc *Incremental) bind(env Env, bound map[string]bool, name string, c *cachedBinding) Env {
+	if c.mono != nil {
+		inc.mono = extend(inc.mono, c.mono)
+		for n := range bound {
+			if bc, ok := inc.cache[n]; ok && n != name {
+				env = env.Add(n, inc.resolve(bc.scheme))
+			}
+		}
+	}
+	return env.Add(name, inc.resolve(c.scheme))
+}
+
+// resolve returns the scheme with the types of its monomorphic TypeVariables substituted. The cached scheme is left as it is.
+func (inc *Incremental) resolve(s *Scheme) *Scheme {
+	if inc.mono == nil {
+		return s
+	}
+	return applyScheme(s, inc.mono)
+}
+
+// infer infers the type of a binding as letrec name = def in name, so the definition may refer to itself.
+func (inc *Incremental) infer(env Env, b Binding) (*cachedBinding, error) {
+	typing := new(Typing)
+	infer := newInferer(env)
+	for _, opt := range inc.opts {
+		opt(infer)
+	}
+	infer.typing = typing
+	// the TypeVariables that have been used may be monomorphic TypeVariables in the schemes of the env
+	infer.count = inc.count
+
+	if err := infer.kindCheckDataTypes(); err != nil {
+		return nil, err
+	}
+	t, ps, sub, err := infer.top(recursiveBinding{b}, nil)
+	if err != nil {
+		return nil, err
+	}
+	expansive := infer.expansive != nil && infer.expansive(b.Body())
+	var mono Subs
+	if typing.Scheme, mono, err = inc.generalize(infer, t, ps, sub, expansive); err != nil {
+		return nil, err
+	}
+
+	deps := make(map[string]*Scheme)
+	for _, inst := range typing.Instantiations {
+		if inst.Name == b.Name() {
+			continue
+		}
+		// a parameter that shadows a name in the env is taken to be a reference to the name. At worst, the binding is inferred again needlessly
+		if s, ok := env.SchemeOf(inst.Name); ok {
+			deps[inst.Name] = s
+		}
+	}
+	return &cachedBinding{
+		hash:   defHash(b.Body()),
+		scheme: typing.Scheme,
+		typing: typing,
+		deps:   deps,
+		mono:   mono,
+	}, nil
+}
+
+// generalize creates the scheme of a binding from its inferred type. It also returns the types of the monomorphic TypeVariables of the env
+// that the solution of the constraints determined.
+//
+// The TypeVariables that the inferer generated are generalized, unless the definition is expansive, or they are in the types of
+// the monomorphic TypeVariables. Those that are not generalized become monomorphic TypeVariables. They are renamed after
+// the TypeVariables that the inferer generated, so they don't clash with the TypeVariables of the bindings that are inferred after it.
+func (inc *Incremental) generalize(infer *inferer, t Type, ps Predicates, sub Subs, expansive bool) (*Scheme, Subs, error) {
+	generated := func(tv TypeVariable) bool { _, ok := infer.levels[tv]; return ok }
+
+	var mono mSubs
+	var tied TypeVarSet
+	if sub != nil {
+		for _, s := range sub.Iter() {
+			if generated(s.Tv) {
+				continue
+			}
+			if mono == nil {
+				mono = make(mSubs)
+			}
+			mono[s.Tv] = s.T
+			tied = append(tied, s.T.FreeTypeVar()...)
+		}
+	}
+
+	ftv := t.FreeTypeVar()
+	for _, p := range ps {
+		for _, tv := range p.FreeTypeVar() {
+			if !ftv.Contains(tv) {
+				return nil, nil, AmbiguityError{Predicates{p}, t}
+			}
+		}
+	}
+
+	// the generated TypeVariables that are not generalized are renamed to the TypeVariables after the ones that the inferer used
+	rename := make(mSubs)
+	monomorphic := func(tv TypeVariable) {
+		if _, ok := rename[tv]; !ok && generated(tv) {
+			rename[tv] = NewTypeVar(infer.count + len(rename))
+		}
+	}
+	var tvs, free TypeVarSet
+	for _, tv := range ftv {
+		if !expansive && generated(tv) && !tied.Contains(tv) {
+			tvs = append(tvs, tv)
+		} else {
+			monomorphic(tv)
+		}
+	}
+	for _, tv := range tied {
+		monomorphic(tv)
+	}
+	inc.count = infer.count + len(rename)
+
+	// the generalized TypeVariables are normalized, without clashing with the monomorphic ones
+	ord := make(TypeVarSet, len(ftv))
+	for i, tv := range ftv {
+		if r, ok := rename[tv]; ok {
+			ord[i] = r.(TypeVariable)
+		} else {
+			ord[i] = tv
+		}
+		if !tvs.Contains(tv) {
+			free = append(free, ord[i])
+		}
+	}
+	names := unusedTypeVars(free, len(tvs))
+	for i, tv := range tvs {
+		ord[ftv.Index(tv)] = names[i]
+	}
+
+	sch := &Scheme{tvs: names}
+	var err error
+	if sch.t, err = t.Normalize(ftv, ord); err != nil {
+		return nil, nil, err
+	}
+	if len(ps) > 0 {
+		sch.ps = make(Predicates, len(ps))
+		for i, p := range ps {
+			if sch.ps[i], err = p.Normalize(ftv, ord); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if mono == nil {
+		return sch, nil, nil
+	}
+	for tv, mt := range mono {
+		mono[tv] = cloneType(mt).Apply(rename).(Type)
+	}
+	return sch, mono, nil
+}
+
+// valid returns true if the definition is the same as the cached one, and the names it refers to have the same schemes in the env.
+func (c *cachedBinding) valid(def Expression, env Env) bool {
+	if c.hash != defHash(def) {
+		return false
+	}
+	for name, s := range c.deps {
+		cur, ok := env.SchemeOf(name)
+		if !ok || (cur != s && !sameScheme(cur, s)) {
+			return false
+		}
+	}
+	return true
+}
+
+// defHash returns the hash of the definition: its Hash if it's a Hasher, or its structural hash otherwise.
+func defHash(def Expression) uint64 {
+	if h, ok := def.(Hasher); ok {
+		return h.Hash()
+	}
+	h := fnv.New64a()
+	hashValue(h, reflect.ValueOf(def), make(map[uintptr]bool))
+	return h.Sum64()
+}
+
+// hashValue writes the type and the contents of the value to the hash. Pointers are followed, unless they lead back to a value that is being hashed.
+func hashValue(h hash.Hash64, v reflect.Value, visiting map[uintptr]bool) {
+	var buf [8]byte
+	writeUint := func(u uint64) {
+		binary.LittleEndian.PutUint64(buf[:], u)
+		h.Write(buf[:])
+	}
+	if !v.IsValid() {
+		writeUint(0)
+		return
+	}
+	io.WriteString(h, v.Type().String())
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		p := v.Pointer()
+		if v.IsNil() || visiting[p] {
+			writeUint(uint64(p))
+			return
+		}
+		visiting[p] = true
+		hashValue(h, v.Elem(), visiting)
+		delete(visiting, p)
+	case reflect.Interface:
+		hashValue(h, v.Elem(), visiting)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			hashValue(h, v.Field(i), visiting)
+		}
+	case reflect.Slice, reflect.Array:
+		writeUint(uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			hashValue(h, v.Index(i), visiting)
+		}
+	case reflect.Map:
+		// the entries are in no particular order, so their hashes are summed
+		var sum uint64
+		iter := v.MapRange()
+		for iter.Next() {
+			eh := fnv.New64a()
+			hashValue(eh, iter.Key(), visiting)
+			hashValue(eh, iter.Value(), visiting)
+			sum += eh.Sum64()
+		}
+		writeUint(sum)
+	case reflect.String:
+		writeUint(uint64(v.Len()))
+		io.WriteString(h, v.String())
+	case reflect.Bool:
+		if v.Bool() {
+			writeUint(1)
+		} else {
+			writeUint(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeUint(math.Float64bits(v.Float()))
+	case reflect.Complex64, reflect.Complex128:
+		writeUint(math.Float64bits(real(v.Complex())))
+		writeUint(math.Float64bits(imag(v.Complex())))
+	default:
+		// functions, channels and unsafe pointers are hashed by their identity
+		writeUint(uint64(v.Pointer()))
+	}
+}
+
+// sameScheme returns true if the schemes are the same. The schemes are expected to be normalized, as the schemes returned by Infer are.
+func sameScheme(a, b *Scheme) bool {
+	if len(a.tvs) != len(b.tvs) || len(a.ps) != len(b.ps) || !a.t.Eq(b.t) {
+		return false
+	}
+	for i, tv := range a.tvs {
+		if b.tvs[i] != tv {
+			return false
+		}
+	}
+	for i, p := range a.ps {
+		if !p.Eq(b.ps[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// recursiveBinding is the expression letrec name = def in name
+type recursiveBinding struct {
+	Binding
+}
+
+func (b recursiveBinding) Def() Expression   { return b.Binding.Body() }
+func (b recursiveBinding) Body() Expression  { return bindingName(b.Name()) }
+func (b recursiveBinding) IsRecursive() bool { return true }
+
+// bindingName is the occurrence of the name of a recursiveBinding in its body
+type bindingName string
+
+func (n bindingName) Name() string     { return string(n) }
+func (n bindingName) Body() Expression { return n }
+func (n bindingName) Type() Type       { return nil }
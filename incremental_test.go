@@ -0,0 +1,217 @@
+package hm
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// hashedλ is a λ that knows its hash
+type hashedλ struct {
+	λ
+	hash uint64
+}
+
+func (n hashedλ) Hash() uint64 { return n.hash }
+
+func TestIncremental(t *testing.T) {
+	program := func(id, k Expression) []Binding {
+		return []Binding{
+			binding{"id", id},
+			binding{"one", app{lit("id"), lit("1")}},
+			binding{"yes", app{lit("id"), lit("true")}},
+			binding{"k", k},
+			binding{"loop", λ{"n", app{lit("loop"), lit("n")}}},
+		}
+	}
+	id := λ{"x", lit("x")}
+	k := λ{"x", λ{"y", lit("x")}}
+
+	updateTests := []struct {
+		name     string
+		bindings []Binding
+
+		reinferred, changed, removed string
+	}{
+		{"first", program(id, k), "[id one yes k loop]", "[id one yes k loop]", "[]"},
+		{"unchanged", program(id, k), "[]", "[]", "[]"},
+		{"edit k", program(id, λ{"x", λ{"y", lit("y")}}), "[k]", "[k]", "[]"},
+		{"edit id", program(λ{"x", lit("2")}, λ{"x", λ{"y", lit("y")}}), "[id one yes]", "[id yes]", "[]"},
+		{"rename the parameter of id", program(λ{"z", lit("z")}, λ{"x", λ{"y", lit("y")}}), "[id one yes]", "[id yes]", "[]"},
+		{"remove k", append(program(λ{"z", lit("z")}, nil)[:3], binding{"loop", λ{"n", app{lit("loop"), lit("n")}}}), "[]", "[]", "[k]"},
+	}
+
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		inc := NewIncremental(nil, opts...)
+		for _, ut := range updateTests {
+			changes, err := inc.Update(ut.bindings)
+			if err != nil {
+				t.Errorf("Test %q: %v", ut.name, err)
+				continue
+			}
+			sort.Strings(changes.Removed)
+			if got := fmt.Sprintf("%v", changes.Reinferred); got != ut.reinferred {
+				t.Errorf("Test %q: Expected %v to be inferred again. Got %v", ut.name, ut.reinferred, got)
+			}
+			if got := fmt.Sprintf("%v", changes.Changed); got != ut.changed {
+				t.Errorf("Test %q: Expected the schemes of %v to change. Got %v", ut.name, ut.changed, got)
+			}
+			if got := fmt.Sprintf("%v", changes.Removed); got != ut.removed {
+				t.Errorf("Test %q: Expected %v to be removed. Got %v", ut.name, ut.removed, got)
+			}
+		}
+
+		schemes := []struct {
+			name, correct string
+		}{
+			{"id", "∀[a]: a → a"},
+			{"one", "∀[]: Float"},
+			{"yes", "∀[]: Bool"},
+			{"loop", "∀[a, b]: a → b"},
+		}
+		for _, s := range schemes {
+			sc, ok := inc.SchemeOf(s.name)
+			if !ok {
+				t.Errorf("Expected %v to be bound", s.name)
+				continue
+			}
+			if got := fmt.Sprintf("%v", sc); got != s.correct {
+				t.Errorf("Expected %v to be %v. Got %v", s.name, s.correct, got)
+			}
+		}
+		if _, ok := inc.SchemeOf("k"); ok {
+			t.Errorf("Expected k to be removed")
+		}
+		if typing, ok := inc.TypingOf("one"); !ok {
+			t.Errorf("Expected one to be typed")
+		} else if got, ok := typing.TypeOf(lit("1")); !ok || fmt.Sprintf("%v", got) != "Float" {
+			t.Errorf("Expected the 1 in one to be Float. Got %v", got)
+		}
+	}
+}
+
+func TestIncremental_Errors(t *testing.T) {
+	inc := NewIncremental(nil)
+	program := []Binding{
+		binding{"id", λ{"x", lit("x")}},
+		binding{"bad", app{lit("id"), lit("undefined")}},
+		binding{"one", app{lit("id"), lit("1")}},
+	}
+	changes, err := inc.Update(program)
+	var te TypeError
+	if !errors.As(err, &te) || te.Code() != CodeUndefinedName {
+		t.Fatalf("Expected an undefined name error. Got %v", err)
+	}
+	if fmt.Sprintf("%v", changes.Changed) != "[id]" {
+		t.Errorf("Expected only id to be inferred before the error. Got %v", changes.Changed)
+	}
+
+	// the binding that failed is inferred again, as well as the ones after it
+	program[1] = binding{"bad", app{lit("id"), lit("true")}}
+	if changes, err = inc.Update(program); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%v", changes.Reinferred) != "[bad one]" {
+		t.Errorf("Expected bad and one to be inferred. Got %v", changes.Reinferred)
+	}
+
+	if _, err = inc.Update(append(program, binding{"id", lit("1")})); err == nil {
+		t.Errorf("Expected an error for a name that is bound twice")
+	}
+
+	// definitions with the same hash are the same, regardless of their contents
+	inc = NewIncremental(nil)
+	if _, err = inc.Update([]Binding{binding{"f", hashedλ{λ{"x", lit("x")}, 1}}}); err != nil {
+		t.Fatal(err)
+	}
+	if changes, err = inc.Update([]Binding{binding{"f", hashedλ{λ{"x", lit("1")}, 1}}}); err != nil || len(changes.Reinferred) != 0 {
+		t.Errorf("Expected f to not be inferred again. Got %v, %v", changes.Reinferred, err)
+	}
+	if changes, err = inc.Update([]Binding{binding{"f", hashedλ{λ{"x", lit("1")}, 2}}}); err != nil || fmt.Sprintf("%v", changes.Changed) != "[f]" {
+		t.Errorf("Expected f to change. Got %v, %v", changes.Changed, err)
+	}
+
+	// other definitions are hashed by their contents, even if they are not comparable
+	inc = NewIncremental(nil)
+	pair := func() Binding { return binding{"pair", tuple{lit("1"), λ{"x", lit("x")}}} }
+	if _, err = inc.Update([]Binding{pair()}); err != nil {
+		t.Fatal(err)
+	}
+	if changes, err = inc.Update([]Binding{pair()}); err != nil || len(changes.Reinferred) != 0 {
+		t.Errorf("Expected the rebuilt pair to not be inferred again. Got %v, %v", changes.Reinferred, err)
+	}
+	edited := pair()
+	if _, err = inc.Update([]Binding{edited}); err != nil {
+		t.Fatal(err)
+	}
+	edited.(binding).def.(tuple)[0] = lit("true")
+	if changes, err = inc.Update([]Binding{edited}); err != nil || fmt.Sprintf("%v", changes.Changed) != "[pair]" {
+		t.Errorf("Expected the pair that is edited in place to change. Got %v, %v", changes.Changed, err)
+	}
+}
+
+func TestIncremental_ValueRestriction(t *testing.T) {
+	a := TypeVariable('a')
+	Unit := TypeConst("Unit")
+	ref := func(t Type) Type { return NewConstructedType("Ref", t) }
+	list := func(t Type) Type { return NewConstructedType("List", t) }
+	env := SimpleEnv{
+		"ref":    NewScheme(TypeVarSet{a}, NewFnType(a, ref(a))),
+		"assign": NewScheme(TypeVarSet{a}, NewFnType(ref(a), a, Unit)),
+		"nil":    NewScheme(TypeVarSet{a}, list(a)),
+		"single": NewScheme(TypeVarSet{a}, NewFnType(a, list(a))),
+	}
+	r := binding{"r", app{lit("ref"), lit("nil")}}
+	x := binding{"x", app{app{lit("assign"), lit("r")}, app{lit("single"), lit("1")}}}
+	y := binding{"y", app{app{lit("assign"), lit("r")}, app{lit("single"), lit("true")}}}
+	get := binding{"get", λ{"u", lit("r")}}
+
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		inc := NewIncremental(env, append(opts, WithValueRestriction(nil))...)
+		changes, err := inc.Update([]Binding{r, x, y})
+		var te TypeError
+		if !errors.As(err, &te) || te.Code() != CodeUnification {
+			t.Errorf("Expected a unification error. Got %v", err)
+		}
+		if got := fmt.Sprintf("%v", changes.Changed); got != "[r x]" {
+			t.Errorf("Expected r and x to be inferred before the error. Got %v", got)
+		}
+
+		// the uses of r determine its type, and the type of the bindings that refer to it
+		if _, err = inc.Update([]Binding{r, get, x}); err != nil {
+			t.Fatal(err)
+		}
+		for name, correct := range map[string]string{"r": "∀[]: Ref (List Float)", "get": "∀[a]: a → Ref (List Float)"} {
+			if sc, _ := inc.SchemeOf(name); fmt.Sprintf("%v", sc) != correct {
+				t.Errorf("Expected the scheme of %v to be %v. Got %v", name, correct, sc)
+			}
+		}
+
+		// without x, r is determined by y instead
+		if _, err = inc.Update([]Binding{r, get, y}); err != nil {
+			t.Fatal(err)
+		}
+		if sc, _ := inc.SchemeOf("r"); fmt.Sprintf("%v", sc) != "∀[]: Ref (List Bool)" {
+			t.Errorf("Expected r to be Ref (List Bool). Got %v", sc)
+		}
+
+		// the schemes that are determined by other bindings change with them
+		if changes, err = inc.Update([]Binding{r, get}); err != nil || fmt.Sprintf("%v", changes.Changed) != "[r get]" {
+			t.Errorf("Expected r and get to change without y. Got %v, %v", changes.Changed, err)
+		}
+		if changes, err = inc.Update([]Binding{r, get, x}); err != nil || fmt.Sprintf("%v", changes.Changed) != "[r get x]" {
+			t.Errorf("Expected r and get to change with x. Got %v, %v", changes.Changed, err)
+		}
+		if changes, err = inc.Update([]Binding{r, get, x}); err != nil || len(changes.Changed) != 0 {
+			t.Errorf("Expected nothing to change. Got %v, %v", changes.Changed, err)
+		}
+
+		// without the value restriction, r is polymorphic
+		inc = NewIncremental(env, opts...)
+		if _, err = inc.Update([]Binding{r, x, y}); err != nil {
+			t.Errorf("Expected r to be polymorphic. Got %v", err)
+		}
+	}
+}
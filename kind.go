@@ -0,0 +1,415 @@
+package hm
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Kind is the type of a type. The types that have values are of kind *, and type constructors are of arrow kinds:
+// List is of kind * → *, and Map is of kind * → * → *.
+type Kind interface {
+	Eq(Kind) bool
+	fmt.Formatter
+	fmt.Stringer
+}
+
+// Star is the kind of the types that have values: *
+var Star Kind = star{}
+
+type star struct{}
+
+func (k star) Eq(other Kind) bool         { return other == Kind(k) }
+func (k star) Format(s fmt.State, c rune) { s.Write([]byte("*")) }
+func (k star) String() string             { return "*" }
+
+// KindArrow is the kind of a type constructor that makes a type of kind To from a type of kind From.
+type KindArrow struct {
+	From, To Kind
+}
+
+// NewKindArrow creates a new KindArrow. Like NewFnType, it is right associative:
+//		NewKindArrow(Star, Star, Star)
+// is * → * → *, which is short hand for * → (* → *)
+func NewKindArrow(ks ...Kind) Kind {
+	if len(ks) < 2 {
+		panic("Expected at least 2 kinds")
+	}
+	if len(ks) == 2 {
+		return KindArrow{ks[0], ks[1]}
+	}
+	return KindArrow{ks[0], NewKindArrow(ks[1:]...)}
+}
+
+func (k KindArrow) Eq(other Kind) bool {
+	ok, isArrow := other.(KindArrow)
+	return isArrow && k.From.Eq(ok.From) && k.To.Eq(ok.To)
+}
+
+func (k KindArrow) Format(s fmt.State, c rune) {
+	if _, ok := k.From.(KindArrow); ok {
+		fmt.Fprintf(s, "(%v) → %v", k.From, k.To)
+		return
+	}
+	fmt.Fprintf(s, "%v → %v", k.From, k.To)
+}
+
+func (k KindArrow) String() string { return fmt.Sprintf("%v", k) }
+
+// kindVar is an unknown kind. kindVars only exist during kind inference.
+type kindVar int
+
+func (k kindVar) Eq(other Kind) bool         { return other == Kind(k) }
+func (k kindVar) Format(s fmt.State, c rune) { s.Write([]byte(k.String())) }
+func (k kindVar) String() string             { return "κ" + strconv.Itoa(int(k)) }
+
+// KindEnv is the kinds of the type constructors, by name. The name of a ConstructedType is the name of its type constructor.
+// Other types (such as TypeConsts) are looked up by their Name().
+type KindEnv map[string]Kind
+
+// Clone clones the KindEnv
+func (e KindEnv) Clone() KindEnv {
+	retVal := make(KindEnv, len(e))
+	for k, v := range e {
+		retVal[k] = v
+	}
+	return retVal
+}
+
+// KindOf infers the kind of the type.
+//
+// The kinds of TypeVariables are inferred from how they are used: the a in Map a b is of whatever kind the first parameter of Map is.
+// Type constructors that are not in the KindEnv are inferred the same way. Kinds that are not constrained at all default to *.
+func KindOf(env KindEnv, t Type) (Kind, error) {
+	ki := newKindInferer(env)
+	k, err := ki.kindOf(t)
+	if err != nil {
+		return nil, err
+	}
+	return ki.zonk(k), nil
+}
+
+// InferKinds infers the kinds of the DataTypes from the kinds of the types of the arguments of their constructors.
+// The DataTypes may refer to each other, and to themselves. The kinds of their parameters that are not constrained default to *.
+// The type variables in the arguments of the constructors have to be parameters of their DataType.
+//
+// The returned KindEnv has the kinds in env, as well as the kinds of the DataTypes.
+func InferKinds(env KindEnv, dts ...*DataType) (KindEnv, error) {
+	ki := newKindInferer(env)
+	params := make([]map[TypeVariable]Kind, len(dts))
+	for i, dt := range dts {
+		params[i] = make(map[TypeVariable]Kind, len(dt.Params))
+		ks := make([]Kind, 0, len(dt.Params)+1)
+		for _, p := range dt.Params {
+			params[i][p] = ki.fresh()
+			ks = append(ks, params[i][p])
+		}
+		ks = append(ks, Star)
+
+		k := ks[0]
+		if len(ks) > 1 {
+			k = NewKindArrow(ks...)
+		}
+		ki.cons[dt.Name] = k
+	}
+
+	for i, dt := range dts {
+		ki.vars = params[i]
+		for _, c := range dt.Constructors {
+			for _, a := range c.Args {
+				for _, tv := range a.FreeTypeVar() {
+					if !dt.Params.Contains(tv) {
+						return nil, UnboundParameterError{DataType: dt.Name, Constructor: c.Name, TypeVar: tv}
+					}
+				}
+				if err := ki.star(a); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	retVal := env.Clone()
+	for _, dt := range dts {
+		retVal[dt.Name] = ki.zonk(ki.cons[dt.Name])
+	}
+	return retVal, nil
+}
+
+// WithKinds makes the inference algorithm check the kinds of the types that come from the user before they are unified:
+// the types in the Env, the annotations, and the DataTypes (whose kinds are inferred with InferKinds).
+// The KindEnv has the kinds of the type constructors.
+func WithKinds(env KindEnv) InferOpt {
+	return func(infer *inferer) { infer.kinds = env }
+}
+
+// kindCheckDataTypes infers the kinds of the DataTypes declared with WithDataTypes, and adds them to infer.kinds.
+func (infer *inferer) kindCheckDataTypes() (err error) {
+	if infer.kinds == nil || len(infer.constructors) == 0 {
+		return nil
+	}
+	var dts []*DataType
+	seen := make(map[*DataType]bool)
+	for _, dt := range infer.constructors {
+		if !seen[dt] {
+			seen[dt] = true
+			dts = append(dts, dt)
+		}
+	}
+	infer.kinds, err = InferKinds(infer.kinds, dts...)
+	return err
+}
+
+// kindCheck checks that the type from the user is the type of values, if the kinds are being checked.
+func (infer *inferer) kindCheck(t Type) error {
+	if infer.kinds == nil || t == nil {
+		return nil
+	}
+	k, err := KindOf(infer.kinds, t)
+	if err != nil {
+		return err
+	}
+	if !k.Eq(Star) {
+		return KindError{Type: t, Expected: Star, Actual: k}
+	}
+	return nil
+}
+
+// kindCheckScheme is kindCheck for the Schemes in the Env. Each Scheme is only checked once.
+func (infer *inferer) kindCheckScheme(s *Scheme) error {
+	if infer.kinds == nil || infer.kinded[s] {
+		return nil
+	}
+	if err := infer.kindCheck(s.t); err != nil {
+		return err
+	}
+	if infer.kinded == nil {
+		infer.kinded = make(map[*Scheme]bool)
+	}
+	infer.kinded[s] = true
+	return nil
+}
+
+type kindInferer struct {
+	env  KindEnv
+	cons KindEnv               // the kinds of the type constructors that are not in env
+	vars map[TypeVariable]Kind // the kinds of the TypeVariables
+	sub  map[kindVar]Kind
+	next kindVar
+}
+
+func newKindInferer(env KindEnv) *kindInferer {
+	return &kindInferer{
+		env:  env,
+		cons: make(KindEnv),
+		vars: make(map[TypeVariable]Kind),
+		sub:  make(map[kindVar]Kind),
+	}
+}
+
+func (ki *kindInferer) fresh() Kind {
+	ki.next++
+	return ki.next
+}
+
+// constructor returns the kind of the named type constructor, if it is known. kindOf gives an unknown type constructor a fresh kind.
+func (ki *kindInferer) constructor(name string) (Kind, bool) {
+	if k, ok := ki.env[name]; ok {
+		return k, true
+	}
+	if k, ok := ki.cons[name]; ok {
+		return k, true
+	}
+	return nil, false
+}
+
+// star checks that t is of kind *
+func (ki *kindInferer) star(t Type) error {
+	k, err := ki.kindOf(t)
+	if err != nil {
+		return err
+	}
+	if err = ki.unify(Star, k); err != nil {
+		return KindError{Type: t, Expected: Star, Actual: ki.zonk(k)}
+	}
+	return nil
+}
+
+// apply returns the kind of the type constructor of kind k applied to the args
+func (ki *kindInferer) apply(t Type, k Kind, args Types) (Kind, error) {
+	for _, a := range args {
+		ak, err := ki.kindOf(a)
+		if err != nil {
+			return nil, err
+		}
+		ret := ki.fresh()
+		if err = ki.unify(k, KindArrow{ak, ret}); err != nil {
+			return nil, KindError{Type: t, Expected: KindArrow{ki.zonk(ak), ki.zonk(ret)}, Actual: ki.zonk(k)}
+		}
+		k = ret
+	}
+	return k, nil
+}
+
+func (ki *kindInferer) kindOf(t Type) (Kind, error) {
+	switch tt := t.(type) {
+	case TypeVariable:
+		if k, ok := ki.vars[tt]; ok {
+			return k, nil
+		}
+		k := ki.fresh()
+		ki.vars[tt] = k
+		return k, nil
+
+	case *ConstructedType:
+		k, ok := ki.constructor(tt.name)
+		if !ok {
+			k = ki.fresh()
+			ki.cons[tt.name] = k
+		}
+		return ki.apply(tt, k, tt.args)
+
+	case *AliasType:
+		if e := tt.Expand(); e != t {
+			return ki.kindOf(e)
+		}
+
+	case *FunctionType:
+		if err := ki.star(tt.a); err != nil {
+			return nil, err
+		}
+		if err := ki.star(tt.b); err != nil {
+			return nil, err
+		}
+		return Star, nil
+
+	case *ForallType:
+		// the quantified TypeVariables shadow the TypeVariables of the enclosing types
+		outer := make(map[TypeVariable]Kind, len(tt.tvs))
+		for _, tv := range tt.tvs {
+			if k, ok := ki.vars[tv]; ok {
+				outer[tv] = k
+			}
+			ki.vars[tv] = ki.fresh()
+		}
+		err := ki.star(tt.t)
+		for _, tv := range tt.tvs {
+			delete(ki.vars, tv)
+			if k, ok := outer[tv]; ok {
+				ki.vars[tv] = k
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return Star, nil
+
+	case *RecursiveType:
+		// the bound TypeVariable stands for the RecursiveType itself, so it's of kind *
+		outer, shadows := ki.vars[tt.tv]
+		ki.vars[tt.tv] = Star
+		err := ki.star(tt.t)
+		delete(ki.vars, tt.tv)
+		if shadows {
+			ki.vars[tt.tv] = outer
+		}
+		if err != nil {
+			return nil, err
+		}
+		return Star, nil
+
+	case *Record:
+		for _, e := range tt.ts {
+			if err := ki.star(e); err != nil {
+				return nil, err
+			}
+		}
+		return Star, nil
+
+	case *Row:
+		// the tail of a row is a row, not a type of kind *
+		for _, f := range tt.fields {
+			if err := ki.star(f.Type); err != nil {
+				return nil, err
+			}
+		}
+		return Star, nil
+	}
+
+	// any other type is either a type constructor in the KindEnv applied to its component types, or a type of kind * made of types of kind *
+	ts := t.Types()
+	if k, ok := ki.constructor(t.Name()); ok {
+		return ki.apply(t, k, ts)
+	}
+	for _, c := range ts {
+		if err := ki.star(c); err != nil {
+			return nil, err
+		}
+	}
+	return Star, nil
+}
+
+func (ki *kindInferer) prune(k Kind) Kind {
+	for {
+		kv, ok := k.(kindVar)
+		if !ok {
+			return k
+		}
+		s, ok := ki.sub[kv]
+		if !ok {
+			return k
+		}
+		k = s
+	}
+}
+
+func (ki *kindInferer) unify(a, b Kind) error {
+	a, b = ki.prune(a), ki.prune(b)
+	if a.Eq(b) {
+		return nil
+	}
+	if av, ok := a.(kindVar); ok {
+		return ki.bind(av, b)
+	}
+	if bv, ok := b.(kindVar); ok {
+		return ki.bind(bv, a)
+	}
+	aa, aIsArrow := a.(KindArrow)
+	ba, bIsArrow := b.(KindArrow)
+	if !aIsArrow || !bIsArrow {
+		return fmt.Errorf("%v ~ %v", a, b)
+	}
+	if err := ki.unify(aa.From, ba.From); err != nil {
+		return err
+	}
+	return ki.unify(aa.To, ba.To)
+}
+
+func (ki *kindInferer) bind(kv kindVar, k Kind) error {
+	if ki.occurs(kv, k) {
+		return fmt.Errorf("%v occurs in %v", kv, k)
+	}
+	ki.sub[kv] = k
+	return nil
+}
+
+func (ki *kindInferer) occurs(kv kindVar, k Kind) bool {
+	switch kk := ki.prune(k).(type) {
+	case kindVar:
+		return kk == kv
+	case KindArrow:
+		return ki.occurs(kv, kk.From) || ki.occurs(kv, kk.To)
+	}
+	return false
+}
+
+// zonk replaces the kindVars in k with what they are bound to. The kindVars that are not bound to anything default to *.
+func (ki *kindInferer) zonk(k Kind) Kind {
+	switch kk := ki.prune(k).(type) {
+	case kindVar:
+		return Star
+	case KindArrow:
+		return KindArrow{ki.zonk(kk.From), ki.zonk(kk.To)}
+	default:
+		return kk
+	}
+}
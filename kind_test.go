@@ -0,0 +1,182 @@
+package hm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestKind_Format(t *testing.T) {
+	kindFormats := []struct {
+		k       Kind
+		correct string
+	}{
+		{Star, "*"},
+		{NewKindArrow(Star, Star), "* → *"},
+		{NewKindArrow(Star, Star, Star), "* → * → *"},
+		{NewKindArrow(NewKindArrow(Star, Star), Star), "(* → *) → *"},
+	}
+	for _, kf := range kindFormats {
+		if got := fmt.Sprintf("%v", kf.k); got != kf.correct {
+			t.Errorf("Expected %v. Got %v", kf.correct, got)
+		}
+	}
+
+	if !NewKindArrow(Star, Star).Eq(KindArrow{Star, Star}) || Star.Eq(NewKindArrow(Star, Star)) {
+		t.Errorf("Unexpected equality of kinds")
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	a, f := TypeVariable('a'), TypeVariable('f')
+	con := NewConstructedType
+	env := KindEnv{
+		"List":  NewKindArrow(Star, Star),
+		"Map":   NewKindArrow(Star, Star, Star),
+		"Apply": NewKindArrow(NewKindArrow(Star, Star), Star, Star),
+	}
+
+	kindOfTests := []struct {
+		name string
+		t    Type
+
+		correct string
+		err     bool
+	}{
+		{"Float", Float, "*", false},
+		{"List", con("List"), "* → *", false},
+		{"List Float", con("List", Float), "*", false},
+		{"Map Float", con("Map", Float), "* → *", false},
+		{"Map Float (List a)", con("Map", Float, con("List", a)), "*", false},
+		{"List List", con("List", con("List")), "", true},
+		{"Map Float Bool Bool", con("Map", Float, Bool, Bool), "", true},
+		{"Apply List Float", con("Apply", con("List"), Float), "*", false},
+		{"Apply Float Float", con("Apply", Float, Float), "", true},
+		{"Apply f a", con("Apply", f, a), "*", false},
+		{"Apply f f", con("Apply", f, f), "", true},
+		{"List → Float", NewFnType(con("List"), Float), "", true},
+		{"(List Float, Map)", NewRecordType("", con("List", Float), con("Map")), "", true},
+		{"Foo Float Float", con("Foo", Float, Float), "*", false},
+		{"Foo Foo", con("Foo", con("Foo")), "", true},
+		{"∀f. Apply f a", NewForallType(TypeVarSet{'f'}, con("Apply", f, a)), "*", false},
+	}
+
+	for _, kts := range kindOfTests {
+		k, err := KindOf(env, kts.t)
+		if kts.err {
+			var ke KindError
+			if !errors.As(err, &ke) {
+				t.Errorf("Test %q: Expected a KindError. Got %v, %v", kts.name, k, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %q: %v", kts.name, err)
+			continue
+		}
+		if got := fmt.Sprintf("%v", k); got != kts.correct {
+			t.Errorf("Test %q: Expected %v. Got %v", kts.name, kts.correct, got)
+		}
+	}
+}
+
+func TestInferKinds(t *testing.T) {
+	a, f := TypeVariable('a'), TypeVariable('f')
+	con := NewConstructedType
+	env := KindEnv{
+		"List":  NewKindArrow(Star, Star),
+		"Apply": NewKindArrow(NewKindArrow(Star, Star), Star, Star),
+	}
+
+	tree := &DataType{Name: "Tree", Params: TypeVarSet{'a'}, Constructors: []Constructor{
+		{Name: "Leaf"},
+		{Name: "Node", Args: Types{a, con("List", con("Tree", a))}},
+	}}
+	wrap := &DataType{Name: "Wrap", Params: TypeVarSet{'f'}, Constructors: []Constructor{
+		{Name: "Wrap", Args: Types{con("Apply", f, Float)}},
+	}}
+	phantom := &DataType{Name: "Phantom", Params: TypeVarSet{'a'}, Constructors: []Constructor{{Name: "P"}}}
+	even := &DataType{Name: "Even", Params: TypeVarSet{'f'}, Constructors: []Constructor{
+		{Name: "E", Args: Types{con("Odd", f)}},
+	}}
+	odd := &DataType{Name: "Odd", Params: TypeVarSet{'f'}, Constructors: []Constructor{
+		{Name: "O", Args: Types{con("Even", f), con("Apply", f, Float)}},
+	}}
+
+	kenv, err := InferKinds(env, tree, wrap, phantom, even, odd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	correct := map[string]string{
+		"Tree":    "* → *",
+		"Wrap":    "(* → *) → *",
+		"Phantom": "* → *",
+		"Even":    "(* → *) → *",
+		"Odd":     "(* → *) → *",
+		"List":    "* → *",
+	}
+	for name, k := range correct {
+		if got := fmt.Sprintf("%v", kenv[name]); got != k {
+			t.Errorf("Expected %v to be of kind %v. Got %v", name, k, got)
+		}
+	}
+	if _, ok := env["Tree"]; ok {
+		t.Errorf("InferKinds should not modify the KindEnv")
+	}
+
+	bad := &DataType{Name: "Bad", Params: TypeVarSet{'a'}, Constructors: []Constructor{
+		{Name: "Bad", Args: Types{con("List", con("List"))}},
+	}}
+	var ke KindError
+	if _, err = InferKinds(env, bad); !errors.As(err, &ke) {
+		t.Errorf("Expected a KindError. Got %v", err)
+	}
+
+	unbound := &DataType{Name: "Unbound", Params: TypeVarSet{'a'}, Constructors: []Constructor{
+		{Name: "U", Args: Types{a, con("List", TypeVariable('b'))}},
+	}}
+	var upe UnboundParameterError
+	if _, err = InferKinds(env, unbound); !errors.As(err, &upe) || upe.TypeVar != 'b' {
+		t.Errorf("Expected an UnboundParameterError for b. Got %v", err)
+	}
+}
+
+func TestInfer_Kinds(t *testing.T) {
+	a := TypeVariable('a')
+	con := NewConstructedType
+	kenv := KindEnv{"List": NewKindArrow(Star, Star)}
+	env := SimpleEnv{
+		"bad":    NewScheme(nil, NewFnType(con("List", con("List")), Float)),
+		"length": NewScheme(TypeVarSet{'a'}, NewFnType(con("List", a), Float)),
+	}
+
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		// without kinds, nothing is checked
+		if _, err := Infer(env, lit("bad"), opts...); err != nil {
+			t.Errorf("Expected no errors without WithKinds. Got %v", err)
+		}
+
+		opts = append(opts, WithKinds(kenv))
+		var te TypeError
+		if _, err := Infer(env, lit("bad"), opts...); !errors.As(err, &te) || te.Code() != CodeKind {
+			t.Errorf("Expected a kind error. Got %v", err)
+		}
+		if sch, err := Infer(env, lit("length"), opts...); err != nil || fmt.Sprintf("%v", sch) != "∀[a]: List a → Float" {
+			t.Errorf("Expected ∀[a]: List a → Float. Got %v, %v", sch, err)
+		}
+
+		ann := annotated{λ{"x", lit("x")}, NewScheme(nil, NewFnType(con("List"), con("List")))}
+		if _, err := Infer(env, ann, opts...); !errors.As(err, &te) || te.Code() != CodeKind {
+			t.Errorf("Expected a kind error in the annotation. Got %v", err)
+		}
+		if err := Check(env, λ{"x", lit("x")}, NewFnType(con("List"), con("List")), opts...); !errors.As(err, &te) || te.Code() != CodeKind {
+			t.Errorf("Expected a kind error in the expected type. Got %v", err)
+		}
+
+		bad := &DataType{Name: "Bad", Constructors: []Constructor{{Name: "Bad", Args: Types{con("List")}}}}
+		if _, err := Infer(env, lit("length"), append(opts, WithDataTypes(bad))...); !errors.As(err, &te) || te.Code() != CodeKind {
+			t.Errorf("Expected a kind error in the DataType. Got %v", err)
+		}
+	}
+}
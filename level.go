@@ -0,0 +1,59 @@
+package hm
+
+// Levels records the level (the let-depth) at which each TypeVariable was created.
+//
+// Levels are used for level-based (Rémy-style) generalization: at a let of level n,
+// only the TypeVariables of the definition whose level is greater than n can be generalized.
+// This means that the Env does not have to be traversed to find its free type variables.
+//
+// TypeVariables that are not in Levels are at level 0, so they will never be generalized.
+// The exception are the TypeVariables that are generated while the constraints are solved (for the tails of rows):
+// they are above every other level until Adjust gives them the level of the TypeVariable whose substitution introduced them.
+type Levels map[TypeVariable]int
+
+// noLevel is the level of the TypeVariables that are generated while the constraints are solved.
+const noLevel = int(^uint(0) >> 1)
+
+// Adjust updates the levels with the substitution: if a TypeVariable of level n is substituted with a type,
+// then the free type variables of that type can be at most at level n.
+//
+// The substitution is expected to be idempotent, like the ones returned by the solvers.
+// Only the new part of a solution needs to be adjusted with: the TypeVariables that it substitutes in the older part
+// already have levels no greater than the TypeVariables that they are in the substitutions of.
+func (l Levels) Adjust(sub Subs) {
+	if sub == nil {
+		return
+	}
+
+	for _, s := range sub.Iter() {
+		lvl := l[s.Tv]
+		ftv := s.T.FreeTypeVar()
+		for _, tv := range ftv {
+			if l[tv] > lvl {
+				l[tv] = lvl
+			}
+		}
+		ReturnTypeVarSet(ftv)
+	}
+}
+
+// GeneralizeLevel creates the most general scheme of t at the given level.
+// Every free type variable of t that has a level greater than the given level is quantified.
+//
+// Unlike Generalize, GeneralizeLevel doesn't need the Env.
+func GeneralizeLevel(level int, levels Levels, t Type) *Scheme {
+	logf("generalizing %v at level %d", t, level)
+	ftv := t.FreeTypeVar()
+	var tvs TypeVarSet
+	for _, tv := range ftv {
+		if levels[tv] > level {
+			tvs = append(tvs, tv)
+		}
+	}
+	ReturnTypeVarSet(ftv)
+
+	return &Scheme{
+		tvs: tvs,
+		t:   t,
+	}
+}
@@ -0,0 +1,111 @@
+package hm
+
+import "testing"
+
+func TestLevels_Adjust(t *testing.T) {
+	levels := Levels{'a': 1, 'b': 2, 'c': 3, 'd': 3, 'f': noLevel}
+	sub := mSubs{
+		'a': NewFnType(TypeVariable('c'), proton),
+		'b': NewFnType(TypeVariable('e'), TypeVariable('f')), // e is not in levels, so it's at level 0. f was generated by a solver
+	}
+	levels.Adjust(sub)
+
+	correct := Levels{'a': 1, 'b': 2, 'c': 1, 'd': 3, 'f': 2}
+	for tv, lvl := range correct {
+		if levels[tv] != lvl {
+			t.Errorf("Expected %v to be at level %d. Got %d instead", tv, lvl, levels[tv])
+		}
+	}
+	if _, ok := levels['e']; ok {
+		t.Errorf("Expected the level of e to remain unset. Got %d", levels['e'])
+	}
+
+	// nil subs are a no-op
+	levels.Adjust(nil)
+
+	// adjusting with the new part of a solution is enough: c is already at the level of a,
+	// so substituting c lowers d to that level too
+	levels.Adjust(mSubs{'c': NewFnType(TypeVariable('d'), proton)})
+	if levels['d'] != 1 {
+		t.Errorf("Expected d to be at level 1. Got %d", levels['d'])
+	}
+}
+
+func TestGeneralizeLevel(t *testing.T) {
+	levels := Levels{'a': 1, 'b': 2, 'c': 3}
+	T := NewFnType(TypeVariable('a'), TypeVariable('b'), TypeVariable('c'), TypeVariable('d'))
+
+	s := GeneralizeLevel(1, levels, T)
+	if !s.tvs.Equals(TypeVarSet{'b', 'c'}) {
+		t.Errorf("Expected b and c to be generalized. Got %v", s)
+	}
+
+	s = GeneralizeLevel(0, levels, T)
+	if !s.tvs.Equals(TypeVarSet{'a', 'b', 'c'}) {
+		t.Errorf("Expected a, b and c to be generalized. Got %v", s)
+	}
+
+	s = GeneralizeLevel(3, levels, T)
+	if len(s.tvs) != 0 {
+		t.Errorf("Expected nothing to be generalized. Got %v", s)
+	}
+}
+
+// countingEnv counts the number of times the free type variables of the env are requested
+type countingEnv struct {
+	SimpleEnv
+	count *int
+}
+
+func (e countingEnv) FreeTypeVar() TypeVarSet { *e.count++; return e.SimpleEnv.FreeTypeVar() }
+func (e countingEnv) Clone() Env              { return countingEnv{e.SimpleEnv.Clone().(SimpleEnv), e.count} }
+
+func TestInfer_Levels(t *testing.T) {
+	env := countingEnv{
+		SimpleEnv: SimpleEnv{
+			"+": &Scheme{tvs: TypeVarSet{'a'}, t: NewFnType(TypeVariable('a'), TypeVariable('a'), TypeVariable('a'))},
+		},
+		count: new(int),
+	}
+
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		// λx. let y = x in y
+		// y must not be generalized, as x is bound in an outer level.
+		expr := λ{"x", let{"y", lit("x"), lit("y")}}
+		sch, err := Infer(env, expr, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sch.t.Eq(NewFnType(TypeVariable('a'), TypeVariable('a'))) {
+			t.Errorf("Expected a → a. Got %v", sch)
+		}
+
+		// let id = λx. x in
+		// let f = λy. let g = id y in g
+		// in f 1
+		expr2 := let{"id", λ{"x", lit("x")},
+			let{"f", λ{"y", let{"g", app{lit("id"), lit("y")}, lit("g")}},
+				app{lit("f"), lit("1")},
+			},
+		}
+		if sch, err = Infer(env, expr2, opts...); err != nil {
+			t.Fatal(err)
+		}
+		if !sch.t.Eq(Float) {
+			t.Errorf("Expected Float. Got %v", sch)
+		}
+
+		// λx. let f = λy. + x y in f
+		expr3 := λ{"x", let{"f", λ{"y", app{app{lit("+"), lit("x")}, lit("y")}}, lit("f")}}
+		if sch, err = Infer(env, expr3, opts...); err != nil {
+			t.Fatal(err)
+		}
+		if !sch.t.Eq(NewFnType(TypeVariable('a'), TypeVariable('a'), TypeVariable('a'))) {
+			t.Errorf("Expected a → a → a. Got %v", sch)
+		}
+	}
+
+	if *env.count != 0 {
+		t.Errorf("Expected the free type variables of the env to never be computed. They were computed %d times", *env.count)
+	}
+}
@@ -0,0 +1,427 @@
+package hm
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// patternType returns the type of the values that the pattern matches.
+// The variables bound by the pattern are added to infer.env, so infer.env should be a clone.
+func (infer *inferer) patternType(p Pattern, origin *Origin) (Type, error) {
+	switch pt := p.(type) {
+	case WildcardPattern:
+		return infer.Fresh(), nil
+
+	case LiteralPattern:
+		if t := pt.Type(); t != nil {
+			return t, nil
+		}
+		if err := infer.lookup(nil, pt.Name()); err != nil {
+			return nil, err
+		}
+		return infer.t, nil
+
+	case VarPattern:
+		tv := infer.Fresh()
+		infer.env.Remove(pt.Name())
+		infer.env.Add(pt.Name(), &Scheme{t: tv})
+		return tv, nil
+
+	case ConstructorPattern:
+		dt, ok := infer.constructors[pt.Name()]
+		if !ok {
+			return nil, UndefinedNameError{pt.Name()}
+		}
+		c, _ := dt.constructor(pt.Name())
+		t, argTypes := dt.instantiate(infer, c)
+
+		args := pt.Args()
+		patTypes := make(Types, len(args))
+		for i, a := range args {
+			var err error
+			if patTypes[i], err = infer.patternType(a, origin); err != nil {
+				return nil, err
+			}
+		}
+		if len(patTypes) != len(argTypes) {
+			return nil, ArityMismatchError{argTypes, patTypes}
+		}
+		for i, at := range argTypes {
+			infer.cs = append(infer.cs, Constraint{patTypes[i], at, origin})
+		}
+		return t, nil
+
+	case RecordPattern:
+		labels, elems, err := recordPattern(pt)
+		if err != nil {
+			return nil, err
+		}
+		fields := make([]Field, len(elems))
+		for i, e := range elems {
+			et, err := infer.patternType(e, origin)
+			if err != nil {
+				return nil, err
+			}
+			fields[i] = Field{Label: labels[i], Type: et}
+		}
+		return NewRow(infer.Fresh(), fields...), nil
+
+	case TuplePattern:
+		elems := pt.Elems()
+		ts := make([]Type, len(elems))
+		for i, e := range elems {
+			var err error
+			if ts[i], err = infer.patternType(e, origin); err != nil {
+				return nil, err
+			}
+		}
+		return NewRecordType("", ts...), nil
+	}
+	return nil, errors.Errorf("Pattern of %T is unhandled", p)
+}
+
+// recordPattern returns the labels and the elements of a RecordPattern. There has to be a label for every element.
+func recordPattern(p RecordPattern) ([]string, []Pattern, error) {
+	labels, elems := p.Labels(), p.Elems()
+	if len(labels) != len(elems) {
+		return nil, nil, errors.Errorf("Record pattern has %d labels but %d elements", len(labels), len(elems))
+	}
+	return labels, elems, nil
+}
+
+// checkPatterns checks that the patterns of the alternatives are exhaustive, and that none of them are redundant.
+//
+// The check is the usefulness algorithm from Maranget's "Warnings for pattern matching".
+func (infer *inferer) checkPatterns(alts []Alternative) error {
+	rows := make([][]*pat, 0, len(alts))
+	for i, alt := range alts {
+		p, err := infer.simplePattern(alt.Pattern())
+		if err != nil {
+			return err
+		}
+		row := []*pat{p}
+		if _, ok := useful(rows, row); !ok {
+			return RedundantPatternError{Index: i, Pattern: row[0].String()}
+		}
+		rows = append(rows, row)
+	}
+
+	if w, ok := useful(rows, []*pat{{}}); ok {
+		return NonExhaustiveError{Missing: w[0].String()}
+	}
+	return nil
+}
+
+type patKind byte
+
+const (
+	wildPat patKind = iota
+	dataPat
+	litPat
+	tuplePat
+	recordPat
+)
+
+// pat is the simplified form of a Pattern that the usefulness algorithm works on.
+// Variables are wildcards, and every other pattern is a constructor applied to patterns.
+type pat struct {
+	kind   patKind
+	name   string   // the name of the constructor or the literal
+	labels []string // the labels of a record pattern
+	args   []*pat
+	dt     *DataType // the DataType of a constructor
+}
+
+func (infer *inferer) simplePattern(p Pattern) (*pat, error) {
+	switch pt := p.(type) {
+	case WildcardPattern:
+		return &pat{}, nil
+	case LiteralPattern:
+		return &pat{kind: litPat, name: pt.Name()}, nil
+	case VarPattern:
+		return &pat{}, nil
+	case ConstructorPattern:
+		args, err := infer.simplePatterns(pt.Args())
+		if err != nil {
+			return nil, err
+		}
+		return &pat{kind: dataPat, name: pt.Name(), args: args, dt: infer.constructors[pt.Name()]}, nil
+	case RecordPattern:
+		labels, elems, err := recordPattern(pt)
+		if err != nil {
+			return nil, err
+		}
+		args, err := infer.simplePatterns(elems)
+		if err != nil {
+			return nil, err
+		}
+		return &pat{kind: recordPat, labels: labels, args: args}, nil
+	case TuplePattern:
+		args, err := infer.simplePatterns(pt.Elems())
+		if err != nil {
+			return nil, err
+		}
+		return &pat{kind: tuplePat, args: args}, nil
+	}
+	return &pat{}, nil
+}
+
+func (infer *inferer) simplePatterns(ps []Pattern) ([]*pat, error) {
+	retVal := make([]*pat, len(ps))
+	for i, p := range ps {
+		var err error
+		if retVal[i], err = infer.simplePattern(p); err != nil {
+			return nil, err
+		}
+	}
+	return retVal, nil
+}
+
+// wilds returns a constructor pattern like c, with wildcards as arguments
+func (p *pat) wilds() *pat {
+	retVal := *p
+	retVal.args = make([]*pat, len(p.args))
+	for i := range retVal.args {
+		retVal.args[i] = &pat{}
+	}
+	return &retVal
+}
+
+// sameCon returns true if both patterns have the same constructor
+func (p *pat) sameCon(other *pat) bool {
+	return p.kind == other.kind && p.name == other.name && len(p.args) == len(other.args)
+}
+
+func (p *pat) String() string {
+	var buf bytes.Buffer
+	p.format(&buf, false)
+	return buf.String()
+}
+
+func (p *pat) format(buf *bytes.Buffer, nested bool) {
+	switch p.kind {
+	case wildPat:
+		buf.WriteString("_")
+	case litPat:
+		buf.WriteString(p.name)
+	case dataPat:
+		if nested && len(p.args) > 0 {
+			buf.WriteString("(")
+			defer buf.WriteString(")")
+		}
+		buf.WriteString(p.name)
+		for _, a := range p.args {
+			buf.WriteString(" ")
+			a.format(buf, true)
+		}
+	case tuplePat:
+		buf.WriteString("(")
+		for i, a := range p.args {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			a.format(buf, false)
+		}
+		buf.WriteString(")")
+	case recordPat:
+		buf.WriteString("{")
+		for i, a := range p.args {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			if i < len(p.labels) {
+				fmt.Fprintf(buf, "%s = ", p.labels[i])
+			}
+			a.format(buf, false)
+		}
+		buf.WriteString("}")
+	}
+}
+
+// useful returns true if there is a value that is matched by q but by none of the rows.
+// It also returns such a value, in the form of patterns.
+func useful(rows [][]*pat, q []*pat) ([]*pat, bool) {
+	if len(q) == 0 {
+		return nil, len(rows) == 0
+	}
+
+	sigma := headCons(rows, q[0])
+	if q[0].kind != wildPat {
+		c := q[0].wilds()
+		if c.kind == recordPat {
+			c = sigma[0]
+		}
+		w, ok := useful(specializeRows(c, rows), specialize(c, q))
+		if !ok {
+			return nil, false
+		}
+		return rebuild(c, w), true
+	}
+
+	if complete(sigma) {
+		for _, c := range allCons(sigma) {
+			if w, ok := useful(specializeRows(c, rows), specialize(c, q)); ok {
+				return rebuild(c, w), true
+			}
+		}
+		return nil, false
+	}
+
+	var def [][]*pat
+	for _, row := range rows {
+		if row[0].kind == wildPat {
+			def = append(def, row[1:])
+		}
+	}
+	w, ok := useful(def, q[1:])
+	if !ok {
+		return nil, false
+	}
+	return append([]*pat{missingCon(sigma)}, w...), true
+}
+
+// headCons returns the distinct constructors of the first column, as patterns with wildcard arguments.
+// Record patterns are merged into one constructor with all the labels of the column.
+func headCons(rows [][]*pat, q *pat) []*pat {
+	var retVal []*pat
+	var record *pat
+	add := func(p *pat) {
+		switch p.kind {
+		case wildPat:
+			return
+		case recordPat:
+			if record == nil {
+				record = &pat{kind: recordPat}
+				retVal = append(retVal, record)
+			}
+			for _, l := range p.labels {
+				if indexOf(record.labels, l) < 0 {
+					record.labels = append(record.labels, l)
+				}
+			}
+			return
+		}
+		for _, c := range retVal {
+			if c.sameCon(p) {
+				return
+			}
+		}
+		retVal = append(retVal, p.wilds())
+	}
+
+	for _, row := range rows {
+		add(row[0])
+	}
+	add(q)
+
+	if record != nil {
+		sort.Strings(record.labels)
+		record.args = make([]*pat, len(record.labels))
+		for i := range record.args {
+			record.args[i] = &pat{}
+		}
+	}
+	return retVal
+}
+
+// complete returns true if the constructors are all the constructors of their type.
+func complete(sigma []*pat) bool {
+	if len(sigma) == 0 {
+		return false
+	}
+	switch sigma[0].kind {
+	case tuplePat, recordPat:
+		return true
+	case dataPat:
+		return missingCon(sigma).kind == wildPat
+	}
+	return false
+}
+
+// allCons returns all the constructors of the type of the constructors.
+func allCons(sigma []*pat) []*pat {
+	if sigma[0].kind != dataPat {
+		return sigma
+	}
+	dt := sigma[0].dt
+	retVal := make([]*pat, len(dt.Constructors))
+	for i, c := range dt.Constructors {
+		retVal[i] = (&pat{kind: dataPat, name: c.Name, args: make([]*pat, len(c.Args)), dt: dt}).wilds()
+	}
+	return retVal
+}
+
+// missingCon returns a constructor that is not in sigma. If there is no such constructor, or if there are too many to choose from, it returns a wildcard.
+func missingCon(sigma []*pat) *pat {
+	if len(sigma) == 0 || sigma[0].kind != dataPat || sigma[0].dt == nil {
+		return &pat{}
+	}
+	for _, c := range allCons(sigma) {
+		found := false
+		for _, s := range sigma {
+			if s.sameCon(c) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return c
+		}
+	}
+	return &pat{}
+}
+
+// specialize specializes the row with the constructor c: if the row starts with c, the arguments of c replace it.
+// It returns nil if the row doesn't match c.
+func specialize(c *pat, row []*pat) []*pat {
+	head := row[0]
+	var args []*pat
+	switch {
+	case head.kind == wildPat:
+		args = c.wilds().args
+	case c.kind == recordPat && head.kind == recordPat:
+		args = make([]*pat, len(c.labels))
+		for i, l := range c.labels {
+			if j := indexOf(head.labels, l); j >= 0 {
+				args[i] = head.args[j]
+			} else {
+				args[i] = &pat{}
+			}
+		}
+	case c.sameCon(head):
+		args = head.args
+	default:
+		return nil
+	}
+	return append(append(make([]*pat, 0, len(args)+len(row)-1), args...), row[1:]...)
+}
+
+func specializeRows(c *pat, rows [][]*pat) [][]*pat {
+	var retVal [][]*pat
+	for _, row := range rows {
+		if s := specialize(c, row); s != nil {
+			retVal = append(retVal, s)
+		}
+	}
+	return retVal
+}
+
+// rebuild is the inverse of specialize: the first arguments of w are put back into the constructor c.
+func rebuild(c *pat, w []*pat) []*pat {
+	p := *c
+	n := len(c.args)
+	p.args = w[:n]
+	return append([]*pat{&p}, w[n:]...)
+}
+
+func indexOf(ss []string, s string) int {
+	for i, v := range ss {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
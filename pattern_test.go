@@ -0,0 +1,195 @@
+package hm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	maybeType = &DataType{
+		Name:   "Maybe",
+		Params: TypeVarSet{'a'},
+		Constructors: []Constructor{
+			{Name: "Nothing"},
+			{Name: "Just", Args: Types{TypeVariable('a')}},
+		},
+	}
+	listType = &DataType{
+		Name:   "List",
+		Params: TypeVarSet{'a'},
+		Constructors: []Constructor{
+			{Name: "Nil"},
+			{Name: "Cons", Args: Types{TypeVariable('a'), NewConstructedType("List", TypeVariable('a'))}},
+		},
+	}
+	colourType = &DataType{
+		Name:         "Colour",
+		Constructors: []Constructor{{Name: "Red"}, {Name: "Green"}, {Name: "Blue"}},
+	}
+)
+
+func just(p Pattern) Pattern               { return conP{"Just", []Pattern{p}} }
+func cons(x, xs Pattern) Pattern           { return conP{"Cons", []Pattern{x, xs}} }
+func con(name string) Pattern              { return conP{name, nil} }
+func alts(as ...Alternative) []Alternative { return as }
+
+func TestInfer_Case(t *testing.T) {
+	a := TypeVariable('a')
+	env := SimpleEnv{
+		"+":    NewScheme(TypeVarSet{'a'}, NewFnType(a, a, a)),
+		"Just": NewScheme(TypeVarSet{'a'}, NewFnType(a, NewConstructedType("Maybe", a))),
+		"pair": NewScheme(TypeVarSet{'a', 'b'}, NewFnType(a, TypeVariable('b'), NewRecordType("", a, TypeVariable('b')))),
+	}
+
+	caseTests := []struct {
+		name string
+		expr Expression
+
+		correct string
+		err     ErrorCode
+	}{
+		{"fromMaybe",
+			λ{"d", λ{"m", caseExpr{lit("m"), alts(alt{con("Nothing"), lit("d")}, alt{just(varP("x")), lit("x")})}}},
+			"∀[a]: a → Maybe a → a", 0},
+		{"case with a literal",
+			λ{"m", caseExpr{lit("m"), alts(alt{just(litP("1")), lit("true")}, alt{wildP{}, lit("false")})}},
+			"∀[]: Maybe Float → Bool", 0},
+		{"head",
+			λ{"xs", caseExpr{lit("xs"), alts(alt{con("Nil"), lit("1")}, alt{cons(varP("x"), wildP{}), lit("x")})}},
+			"∀[]: List Float → Float", 0},
+		{"tuple",
+			λ{"p", caseExpr{lit("p"), alts(alt{tupleP{varP("x"), varP("y")}, app{app{lit("pair"), lit("y")}, lit("x")}})}},
+			"∀[a, b]: (a, b) → (b, a)", 0},
+		{"record",
+			λ{"r", caseExpr{lit("r"), alts(alt{recordP{[]string{"x"}, []Pattern{varP("v")}}, lit("v")})}},
+			"∀[a, b]: {x: a | b} → a", 0},
+		{"pattern variables shadow",
+			λ{"x", caseExpr{app{lit("Just"), lit("1")}, alts(alt{just(varP("x")), lit("x")}, alt{con("Nothing"), lit("1")})}},
+			"∀[a]: a → Float", 0},
+		{"variables are monomorphic",
+			λ{"x", caseExpr{lit("x"), alts(alt{varP("y"), app{app{lit("+"), lit("y")}, lit("1")}})}},
+			"∀[]: Float → Float", 0},
+
+		{"branches of different types",
+			λ{"m", caseExpr{lit("m"), alts(alt{con("Nothing"), lit("1")}, alt{just(wildP{}), lit("true")})}},
+			"", CodeUnification},
+		{"patterns of different types",
+			λ{"m", caseExpr{lit("m"), alts(alt{con("Nothing"), lit("1")}, alt{con("Nil"), lit("1")})}},
+			"", CodeUnification},
+		{"undefined constructor",
+			λ{"m", caseExpr{lit("m"), alts(alt{con("Left"), lit("1")})}},
+			"", CodeUndefinedName},
+		{"wrong number of arguments",
+			λ{"m", caseExpr{lit("m"), alts(alt{conP{"Just", []Pattern{wildP{}, wildP{}}}, lit("1")})}},
+			"", CodeArityMismatch},
+
+		{"non-exhaustive",
+			λ{"m", caseExpr{lit("m"), alts(alt{con("Nothing"), lit("1")})}},
+			"", CodeNonExhaustive},
+		{"non-exhaustive literals",
+			λ{"m", caseExpr{lit("m"), alts(alt{litP("1"), lit("1")}, alt{litP("2"), lit("1")})}},
+			"", CodeNonExhaustive},
+		{"redundant",
+			λ{"m", caseExpr{lit("m"), alts(alt{wildP{}, lit("1")}, alt{con("Nothing"), lit("1")})}},
+			"", CodeRedundantPattern},
+	}
+
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		opts = append(opts, WithDataTypes(maybeType, listType, colourType))
+		for _, cts := range caseTests {
+			sch, err := Infer(env, cts.expr, opts...)
+			if cts.err != 0 {
+				var te TypeError
+				if !errors.As(err, &te) || te.Code() != cts.err {
+					t.Errorf("Test %q: Expected a %v error. Got %v, %v", cts.name, cts.err, sch, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("Test %q: %+v", cts.name, err)
+				continue
+			}
+			if got := fmt.Sprintf("%v", sch); got != cts.correct {
+				t.Errorf("Test %q: Expected %v. Got %v", cts.name, cts.correct, got)
+			}
+		}
+	}
+
+	// a record pattern needs a label for every element
+	bad := λ{"r", caseExpr{lit("r"), alts(alt{recordP{[]string{"x"}, []Pattern{varP("v"), varP("w")}}, lit("v")})}}
+	if sch, err := Infer(env, bad); err == nil {
+		t.Errorf("Expected an error for a record pattern with more elements than labels. Got %v", sch)
+	}
+}
+
+func TestCheckPatterns(t *testing.T) {
+	infer := newInferer(nil)
+	WithDataTypes(maybeType, listType, colourType)(infer)
+
+	patternTests := []struct {
+		name string
+		pats []Pattern
+
+		missing   string
+		redundant int // -1 if there is no redundant pattern
+	}{
+		{"wildcard", []Pattern{wildP{}}, "", -1},
+		{"variable", []Pattern{varP("x")}, "", -1},
+		{"all constructors", []Pattern{con("Red"), con("Green"), con("Blue")}, "", -1},
+		{"missing constructor", []Pattern{con("Red"), con("Blue")}, "Green", -1},
+		{"nested", []Pattern{con("Nothing"), just(con("Red")), just(con("Green"))}, "Just Blue", -1},
+		{"nested with wildcard", []Pattern{just(wildP{}), con("Nothing")}, "", -1},
+		{"lists", []Pattern{con("Nil"), cons(wildP{}, con("Nil"))}, "Cons _ (Cons _ _)", -1},
+		{"literals", []Pattern{litP("1")}, "_", -1},
+		{"literals and wildcard", []Pattern{litP("1"), litP("2"), wildP{}}, "", -1},
+		{"tuples", []Pattern{tupleP{con("Nothing"), wildP{}}, tupleP{wildP{}, con("Nothing")}}, "(Just _, Just _)", -1},
+		{"records with different labels", []Pattern{
+			recordP{[]string{"x"}, []Pattern{con("Nothing")}},
+			recordP{[]string{"y"}, []Pattern{con("Nothing")}},
+			recordP{[]string{"x", "y"}, []Pattern{just(wildP{}), just(wildP{})}},
+		}, "", -1},
+		{"records with missing", []Pattern{
+			recordP{[]string{"x"}, []Pattern{con("Nothing")}},
+			recordP{[]string{"y"}, []Pattern{con("Nothing")}},
+		}, "{x = Just _, y = Just _}", -1},
+
+		{"redundant wildcard", []Pattern{wildP{}, wildP{}}, "", 1},
+		{"redundant constructor", []Pattern{con("Red"), con("Green"), con("Red"), con("Blue")}, "", 2},
+		{"redundant after all constructors", []Pattern{con("Nothing"), just(varP("x")), wildP{}}, "", 2},
+		{"redundant literal", []Pattern{litP("1"), litP("1"), wildP{}}, "", 1},
+		{"redundant nested", []Pattern{just(wildP{}), just(con("Red")), con("Nothing")}, "", 1},
+		{"redundant tuple", []Pattern{tupleP{wildP{}, con("Nothing")}, tupleP{just(wildP{}), wildP{}}, tupleP{just(wildP{}), con("Nothing")}, wildP{}}, "", 2},
+	}
+
+	for _, pts := range patternTests {
+		var as []Alternative
+		for _, p := range pts.pats {
+			as = append(as, alt{p, lit("1")})
+		}
+
+		err := infer.checkPatterns(as)
+		switch e := err.(type) {
+		case nil:
+			if pts.missing != "" || pts.redundant >= 0 {
+				t.Errorf("Test %q: Expected an error", pts.name)
+			}
+		case NonExhaustiveError:
+			if e.Missing != pts.missing {
+				t.Errorf("Test %q: Expected %q to be missing. Got %v", pts.name, pts.missing, err)
+			}
+		case RedundantPatternError:
+			if e.Index != pts.redundant {
+				t.Errorf("Test %q: Expected alternative %d to be redundant. Got %v", pts.name, pts.redundant, err)
+			}
+		default:
+			t.Errorf("Test %q: Unexpected error %v", pts.name, err)
+		}
+	}
+
+	bad := alt{recordP{[]string{"x", "y"}, []Pattern{wildP{}}}, lit("1")}
+	if err := infer.checkPatterns([]Alternative{bad}); err == nil {
+		t.Errorf("Expected an error for a record pattern with more labels than elements")
+	}
+}
@@ -158,5 +158,6 @@ func ReturnFnType(fnt *FunctionType) {
 
 	fnt.a = nil
 	fnt.b = nil
+	fnt.eff = nil
 	fnTypePool.Put(fnt)
 }
@@ -1,6 +1,9 @@
 package hm
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+)
 
 func TestSubsPool(t *testing.T) {
 	var def TypeVariable
@@ -120,3 +123,74 @@ func TestFnTypeOol(t *testing.T) {
 	}
 
 }
+
+// Benchmarks for the solvers.
+//
+// The reference solver is at least quadratic in the number of constraints (cubic for long chains),
+// so the larger inputs take a very long time. Use -bench '/UnionFind' to run only the union-find solver,
+// or -benchtime=1x to run every benchmark once.
+
+var solverBenchSizes = []int{1000, 10000, 100000}
+
+// chainConstraints generates a0 = a1 → proton, a1 = a2 → proton, ... which is the worst case for the reference solver.
+func chainConstraints(n int) Constraints {
+	cs := make(Constraints, n)
+	for i := range cs {
+		cs[i] = Constraint{a: NewTypeVar(i), b: NewFnType(NewTypeVar(i+1), proton)}
+	}
+	return cs
+}
+
+// aliasConstraints generates a0 = a1, a1 = a2, ... a(n-1) = proton
+func aliasConstraints(n int) Constraints {
+	cs := make(Constraints, n)
+	for i := 0; i < n-1; i++ {
+		cs[i] = Constraint{a: NewTypeVar(i), b: NewTypeVar(i + 1)}
+	}
+	cs[n-1] = Constraint{a: NewTypeVar(n - 1), b: proton}
+	return cs
+}
+
+// appConstraints generates the kinds of constraints that function applications generate: f = a → b, where each f, a and b is fresh.
+func appConstraints(n int) Constraints {
+	cs := make(Constraints, n)
+	for i := range cs {
+		cs[i] = Constraint{a: NewTypeVar(3 * i), b: NewFnType(NewTypeVar(3*i+1), NewTypeVar(3*i+2))}
+	}
+	return cs
+}
+
+func benchmarkSolve(b *testing.B, gen func(int) Constraints, size int, uf bool) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		cs := gen(size)
+		b.StartTimer()
+
+		if uf {
+			s := newUFSolver()
+			s.solve(cs)
+			if s.err != nil {
+				b.Fatal(s.err)
+			}
+			continue
+		}
+		s := newSolver()
+		s.solve(cs)
+		if s.err != nil {
+			b.Fatal(s.err)
+		}
+	}
+}
+
+func benchmarkSolvers(b *testing.B, gen func(int) Constraints) {
+	for _, size := range solverBenchSizes {
+		b.Run(fmt.Sprintf("UnionFind-%d", size), func(b *testing.B) { benchmarkSolve(b, gen, size, true) })
+	}
+	for _, size := range solverBenchSizes[:2] {
+		b.Run(fmt.Sprintf("Solver-%d", size), func(b *testing.B) { benchmarkSolve(b, gen, size, false) })
+	}
+}
+
+func BenchmarkSolve_Chain(b *testing.B) { benchmarkSolvers(b, chainConstraints) }
+func BenchmarkSolve_Alias(b *testing.B) { benchmarkSolvers(b, aliasConstraints) }
+func BenchmarkSolve_App(b *testing.B)   { benchmarkSolvers(b, appConstraints) }
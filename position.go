@@ -0,0 +1,75 @@
+package hm
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Position is a position in the source code. The zero value is an invalid position.
+type Position struct {
+	Filename string
+	Offset   int // byte offset, starting at 0
+	Line     int // line number, starting at 1
+	Column   int // column number, starting at 1
+}
+
+// IsValid returns true if the position is valid
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+func (p Position) String() string {
+	s := p.Filename
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
+
+// A Positioner is an Expression/AST node that knows where it is in the source code.
+// It is optional - Expressions that implement Positioner will have their spans reported in the errors that arise while inferring them.
+type Positioner interface {
+	Pos() (start, end Position)
+}
+
+// PositionError is an error annotated with the span of the innermost Expression that implements Positioner.
+type PositionError struct {
+	Start, End Position
+	Err        error
+}
+
+func (e *PositionError) Error() string { return fmt.Sprintf("%v: %v", e.Start, e.Err) }
+
+// Unwrap returns the underlying error. This allows the use of errors.Is and errors.As
+func (e *PositionError) Unwrap() error { return e.Err }
+
+// Cause returns the underlying error. This allows the use of errors.Cause
+func (e *PositionError) Cause() error { return e.Err }
+
+// Span returns the start and end positions.
+func (e *PositionError) Span() (start, end Position) { return e.Start, e.End }
+
+// withPos annotates the error with the span of the expression (or constraint Origin), if it is a Positioner
+// and the error hasn't already been annotated by a more deeply nested Expression.
+func withPos(expr interface{}, err error) error {
+	p, ok := expr.(Positioner)
+	if !ok || err == nil {
+		return err
+	}
+
+	start, end := p.Pos()
+	if !start.IsValid() {
+		return err
+	}
+
+	var pe *PositionError
+	if errors.As(err, &pe) {
+		return err
+	}
+	return &PositionError{Start: start, End: end, Err: err}
+}
@@ -0,0 +1,80 @@
+package hm
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestPosition(t *testing.T) {
+	var p Position
+	if p.IsValid() {
+		t.Error("Expected the zero Position to be invalid")
+	}
+	if p.String() != "-" {
+		t.Errorf("Expected \"-\". Got %q", p.String())
+	}
+
+	p = Position{Line: 3, Column: 14}
+	if p.String() != "3:14" {
+		t.Errorf("Expected \"3:14\". Got %q", p.String())
+	}
+
+	p.Filename = "foo.hm"
+	if p.String() != "foo.hm:3:14" {
+		t.Errorf("Expected \"foo.hm:3:14\". Got %q", p.String())
+	}
+}
+
+func TestInfer_Position(t *testing.T) {
+	env := SimpleEnv{
+		"+": &Scheme{tvs: TypeVarSet{'a'}, t: NewFnType(TypeVariable('a'), TypeVariable('a'), TypeVariable('a'))},
+	}
+
+	// the innermost positioned node is reported
+	undefined := posLit{lit("undefined"), newSpan(2, 10, 9)}
+	expr := posλ{
+		λ{"x", posApp{app{lit("+"), app{lit("+"), undefined}}, newSpan(2, 1, 20)}},
+		newSpan(1, 1, 30),
+	}
+
+	_, err := Infer(env, expr)
+	var pe *PositionError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Expected a *PositionError. Got %v instead", err)
+	}
+	start, end := pe.Span()
+	if start != undefined.start || end != undefined.end {
+		t.Errorf("Expected the span of the undefined literal %v-%v. Got %v-%v instead", undefined.start, undefined.end, start, end)
+	}
+
+	// the underlying error is still accessible
+	var ne UndefinedNameError
+	if !errors.As(err, &ne) || ne.Name != "undefined" {
+		t.Errorf("Expected an UndefinedNameError. Got %v", err)
+	}
+
+	// only the innermost span is recorded
+	count := 0
+	for e := error(pe); e != nil; e = errors.Unwrap(e) {
+		if _, ok := e.(*PositionError); ok {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected exactly one *PositionError in the chain. Got %d", count)
+	}
+
+	// if the innermost node is not positioned, the closest positioned ancestor is used
+	expr = posλ{λ{"x", app{lit("+"), lit("undefined")}}, newSpan(1, 1, 30)}
+	_, err = Infer(env, expr)
+	if !errors.As(err, &pe) {
+		t.Fatalf("Expected a *PositionError. Got %v instead", err)
+	}
+	if pe.Start != expr.start {
+		t.Errorf("Expected the span of the lambda. Got %v", pe.Start)
+	}
+	if s := err.Error(); s[:len("test.hm:1:1: ")] != "test.hm:1:1: " {
+		t.Errorf("Expected the error message to begin with the position. Got %q", s)
+	}
+}
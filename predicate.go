@@ -0,0 +1,128 @@
+package hm
+
+import "fmt"
+
+// Predicate is a type class constraint on types. For example, `Num a` says that the type a has to be an instance of the class Num.
+// Predicates with more than one type represent multi-parameter type classes.
+type Predicate struct {
+	Class string
+	Types Types
+}
+
+// NewPredicate creates a new Predicate
+func NewPredicate(class string, ts ...Type) Predicate {
+	return Predicate{
+		Class: class,
+		Types: ts,
+	}
+}
+
+func (p Predicate) Apply(sub Subs) Substitutable {
+	if sub == nil {
+		return p
+	}
+	ts := make(Types, len(p.Types))
+	for i, t := range p.Types {
+		ts[i] = cloneType(t).Apply(sub).(Type)
+	}
+	return Predicate{Class: p.Class, Types: ts}
+}
+
+func (p Predicate) FreeTypeVar() TypeVarSet {
+	var retVal TypeVarSet
+	for _, t := range p.Types {
+		retVal = t.FreeTypeVar().Union(retVal)
+	}
+	return retVal
+}
+
+// Eq returns true if both predicates are of the same class and on the same types
+func (p Predicate) Eq(other Predicate) bool {
+	if p.Class != other.Class || len(p.Types) != len(other.Types) {
+		return false
+	}
+	for i, t := range p.Types {
+		if !t.Eq(other.Types[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p Predicate) Normalize(k, v TypeVarSet) (Predicate, error) {
+	ts := make(Types, len(p.Types))
+	var err error
+	for i, t := range p.Types {
+		if ts[i], err = t.Normalize(k, v); err != nil {
+			return Predicate{}, err
+		}
+	}
+	return Predicate{Class: p.Class, Types: ts}, nil
+}
+
+func (p Predicate) Format(s fmt.State, c rune) {
+	if p.Class == SubtypeClass && len(p.Types) == 2 {
+		fmt.Fprintf(s, "%v <: %v", p.Types[0], p.Types[1])
+		return
+	}
+	s.Write([]byte(p.Class))
+	for _, t := range p.Types {
+		if ts := t.Types(); len(ts) > 0 {
+			fmt.Fprintf(s, " (%v)", t)
+			ReturnTypes(ts)
+			continue
+		}
+		fmt.Fprintf(s, " %v", t)
+	}
+}
+
+func (p Predicate) String() string { return fmt.Sprintf("%v", p) }
+
+// Predicates is a slice of Predicate. Like a Predicate, it is also a Substitutable
+type Predicates []Predicate
+
+func (ps Predicates) Apply(sub Subs) Substitutable {
+	if sub == nil || len(ps) == 0 {
+		return ps
+	}
+	retVal := make(Predicates, len(ps))
+	for i, p := range ps {
+		retVal[i] = p.Apply(sub).(Predicate)
+	}
+	return retVal
+}
+
+func (ps Predicates) FreeTypeVar() TypeVarSet {
+	var retVal TypeVarSet
+	for _, p := range ps {
+		retVal = p.FreeTypeVar().Union(retVal)
+	}
+	return retVal
+}
+
+// Contains returns true if an equal predicate is in the slice
+func (ps Predicates) Contains(p Predicate) bool {
+	for _, q := range ps {
+		if q.Eq(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ps Predicates) Format(s fmt.State, c rune) {
+	switch len(ps) {
+	case 0:
+	case 1:
+		fmt.Fprintf(s, "%v", ps[0])
+	default:
+		s.Write([]byte("("))
+		for i, p := range ps {
+			if i < len(ps)-1 {
+				fmt.Fprintf(s, "%v, ", p)
+			} else {
+				fmt.Fprintf(s, "%v)", p)
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package hm
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPredicate(t *testing.T) {
+	p := NewPredicate("Num", TypeVariable('a'))
+	if fmt.Sprintf("%v", p) != "Num a" {
+		t.Errorf("Expected \"Num a\". Got %q instead", p)
+	}
+
+	ftv := p.FreeTypeVar()
+	if len(ftv) != 1 || ftv[0] != TypeVariable('a') {
+		t.Errorf("Expected the free type variables to be [a]. Got %v instead", ftv)
+	}
+
+	p2 := p.Apply(mSubs{'a': proton}).(Predicate)
+	if !p2.Eq(NewPredicate("Num", proton)) {
+		t.Errorf("Expected Num proton. Got %v instead", p2)
+	}
+	if !p.Eq(NewPredicate("Num", TypeVariable('a'))) {
+		t.Errorf("Apply should not mutate the original predicate. Got %v", p)
+	}
+	if p.Eq(NewPredicate("Eq", TypeVariable('a'))) {
+		t.Error("Predicates of different classes should not be equal")
+	}
+
+	// composite types are parenthesized
+	p = NewPredicate("Convert", list{TypeVariable('a')}, TypeVariable('b'))
+	if fmt.Sprintf("%v", p) != "Convert (List a) b" {
+		t.Errorf("Expected \"Convert (List a) b\". Got %q instead", p)
+	}
+
+	p, err := p.Normalize(TypeVarSet{'a', 'b'}, TypeVarSet{'b', 'c'})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Eq(NewPredicate("Convert", list{TypeVariable('b')}, TypeVariable('c'))) {
+		t.Errorf("Normalize failed. Got %v", p)
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	ps := Predicates{NewPredicate("Num", TypeVariable('a')), NewPredicate("Show", TypeVariable('b'))}
+	if fmt.Sprintf("%v", ps) != "(Num a, Show b)" {
+		t.Errorf("Expected \"(Num a, Show b)\". Got %q instead", fmt.Sprintf("%v", ps))
+	}
+	if fmt.Sprintf("%v", ps[:1]) != "Num a" {
+		t.Errorf("Expected \"Num a\". Got %q instead", fmt.Sprintf("%v", ps[:1]))
+	}
+
+	ftv := ps.FreeTypeVar()
+	if !ftv.Contains('a') || !ftv.Contains('b') {
+		t.Errorf("Expected the free type variables to contain a and b. Got %v", ftv)
+	}
+
+	ps = ps.Apply(mSubs{'b': proton}).(Predicates)
+	if !ps.Contains(NewPredicate("Show", proton)) {
+		t.Errorf("Expected Show proton in %v", ps)
+	}
+	if ps.Contains(NewPredicate("Show", neutron)) {
+		t.Errorf("Did not expect Show neutron in %v", ps)
+	}
+}
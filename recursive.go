@@ -0,0 +1,136 @@
+package hm
+
+import "fmt"
+
+// RecursiveType is an equi-recursive type: μa. T is the type that is equal to T with a replaced by μa. T itself.
+// For example, μa. a → Float is the type of the functions that can be applied to themselves:
+//		μa. a → Float = (μa. a → Float) → Float = ((μa. a → Float) → Float) → Float = ...
+//
+// RecursiveTypes are equal if their infinite unfoldings are. So μa. a → Float is equal to μb. (b → Float) → Float.
+//
+// By default, a TypeVariable that occurs in the type it is unified with is an OccursCheckError.
+// WithRecursiveTypes makes the inference algorithm bind it to a RecursiveType instead.
+type RecursiveType struct {
+	tv TypeVariable
+	t  Type
+}
+
+// NewRecursiveType creates a new RecursiveType μtv. t
+func NewRecursiveType(tv TypeVariable, t Type) *RecursiveType {
+	return &RecursiveType{
+		tv: tv,
+		t:  t,
+	}
+}
+
+// TypeVar returns the TypeVariable that stands for the RecursiveType in its body
+func (t *RecursiveType) TypeVar() TypeVariable { return t.tv }
+
+// Body returns the body of the RecursiveType
+func (t *RecursiveType) Body() Type { return t.t }
+
+// Unfold returns the body with the TypeVariable replaced by the RecursiveType: μa. a → Float unfolds to (μa. a → Float) → Float
+func (t *RecursiveType) Unfold() Type {
+	return cloneType(t.t).Apply(mSubs{t.tv: t}).(Type)
+}
+
+func (t *RecursiveType) Name() string { return "μ" }
+
+// Apply applies the substitution to the body. The bound TypeVariable is not substituted,
+// and it is renamed if it would capture the free TypeVariables of the substituted types.
+func (t *RecursiveType) Apply(sub Subs) Substitutable {
+	if sub == nil {
+		return t
+	}
+	sub = sub.Clone()
+	defer ReturnSubs(sub)
+	sub = sub.Remove(t.tv)
+
+	tv, body := t.tv, cloneType(t.t)
+	for _, s := range sub.Iter() {
+		if s.T.FreeTypeVar().Contains(tv) {
+			fresh := unusedTypeVars(boundUsed(TypeVarSet{t.tv}, t.t, sub), 1)[0]
+			body = body.Apply(mSubs{tv: fresh}).(Type)
+			tv = fresh
+			break
+		}
+	}
+	return &RecursiveType{tv: tv, t: body.Apply(sub).(Type)}
+}
+
+func (t *RecursiveType) FreeTypeVar() TypeVarSet {
+	return t.t.FreeTypeVar().Difference(TypeVarSet{t.tv})
+}
+
+// Normalize normalizes the free TypeVariables of the body with k and v. The bound TypeVariable is named after the names in v.
+func (t *RecursiveType) Normalize(k, v TypeVarSet) (Type, error) {
+	// the bound TypeVariable comes first, as it shadows the TypeVariables of the enclosing types
+	next := unusedTypeVars(v, 1)[0]
+	k2 := append(TypeVarSet{t.tv}, k...)
+	v2 := append(TypeVarSet{next}, v...)
+
+	body, err := t.t.Normalize(k2, v2)
+	if err != nil {
+		return nil, err
+	}
+	return &RecursiveType{tv: next, t: body}, nil
+}
+
+func (t *RecursiveType) Types() Types { return Types{t.t} }
+
+// Eq returns true if the infinite unfoldings of the types are the same.
+func (t *RecursiveType) Eq(other Type) bool {
+	if ot, ok := other.(*RecursiveType); ok && ot == t {
+		return true
+	}
+	return equiRecursive(t, other)
+}
+
+func (t *RecursiveType) Format(s fmt.State, c rune) { fmt.Fprintf(s, "μ%v. %v", t.tv, t.t) }
+
+func (t *RecursiveType) String() string { return fmt.Sprintf("%v", t) }
+
+// Clone implements Cloner
+func (t *RecursiveType) Clone() interface{} {
+	return &RecursiveType{
+		tv: t.tv,
+		t:  cloneType(t.t),
+	}
+}
+
+// WithRecursiveTypes makes the inference algorithm bind a TypeVariable that occurs in the type it is unified with to a RecursiveType:
+// a ~ a → b binds a to μa. a → b, where it would otherwise be an OccursCheckError.
+//
+// Recursive types are solved with the union-find based unifier (as if WithUnionFind were given), where the cyclic types are cycles in the forest.
+func WithRecursiveTypes() InferOpt {
+	return func(infer *inferer) {
+		infer.recursive = true
+		infer.unionFind = true
+	}
+}
+
+// unifyRecursive unifies two types where at least one is a RecursiveType.
+// The RecursiveTypes are rolled up into cycles in a union-find forest, so that the types are compared coinductively.
+func unifyRecursive(f Fresher, a, b Type) (Subs, error) {
+	s := newUFSolver()
+	s.fresher = f
+	if err := s.unify(a, b); err != nil {
+		return nil, err
+	}
+	s.finish()
+	return s.sub, s.err
+}
+
+// equiRecursive returns true if the infinite unfoldings of the types are the same.
+// The free TypeVariables are replaced with Skolems, so the types are equal if and only if they unify.
+func equiRecursive(a, b Type) bool {
+	f := newTypesFresher(a, b)
+	ftv := a.FreeTypeVar().Union(b.FreeTypeVar())
+	sub := make(mSubs, len(ftv))
+	for _, tv := range ftv {
+		sub[tv] = freshSkolem(f, tv)
+	}
+	s := newUFSolver()
+	s.fresher = f
+	return s.unify(cloneType(a).Apply(sub).(Type), cloneType(b).Apply(sub).(Type)) == nil
+}
@@ -0,0 +1,155 @@
+package hm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestRecursiveType(t *testing.T) {
+	a, b, c := TypeVariable('a'), TypeVariable('b'), TypeVariable('c')
+	self := NewRecursiveType(a, NewFnType(a, Float))
+
+	recursiveFormats := []struct {
+		t       Type
+		correct string
+	}{
+		{self, "μa. a → Float"},
+		{self.Unfold(), "(μa. a → Float) → Float"},
+		{NewFnType(self, self), "(μa. a → Float) → μa. a → Float"},
+		{NewConstructedType("List", self), "List (μa. a → Float)"},
+	}
+	for _, rf := range recursiveFormats {
+		if got := fmt.Sprintf("%v", rf.t); got != rf.correct {
+			t.Errorf("Expected %v. Got %v", rf.correct, got)
+		}
+	}
+
+	// equality is coinductive
+	if !self.Eq(NewRecursiveType(b, NewFnType(b, Float))) {
+		t.Errorf("Expected the RecursiveTypes to be equal up to the name of the bound TypeVariable")
+	}
+	if !self.Eq(NewRecursiveType(b, NewFnType(NewFnType(b, Float), Float))) {
+		t.Errorf("Expected μa. a → Float to be equal to μb. (b → Float) → Float")
+	}
+	if !self.Eq(self.Unfold()) {
+		t.Errorf("Expected a RecursiveType to be equal to its unfolding")
+	}
+	if self.Eq(NewRecursiveType(b, NewFnType(b, Bool))) || self.Eq(NewFnType(c, Float)) {
+		t.Errorf("Expected the RecursiveTypes to be different")
+	}
+	if !NewRecursiveType(a, NewFnType(a, b)).Eq(NewRecursiveType(c, NewFnType(c, b))) || NewRecursiveType(a, NewFnType(a, b)).Eq(NewRecursiveType(a, NewFnType(a, c))) {
+		t.Errorf("Expected the free TypeVariables to be compared by name")
+	}
+
+	// the bound TypeVariable is not free, and it is not substituted
+	r := NewRecursiveType(a, NewFnType(a, b))
+	if ftv := r.FreeTypeVar(); !ftv.Equals(TypeVarSet{'b'}) {
+		t.Errorf("Expected the free TypeVariables to be [b]. Got %v", ftv)
+	}
+	got := r.Apply(mSubs{'a': Float, 'b': a}).(Type)
+	if ftv := got.FreeTypeVar(); !ftv.Equals(TypeVarSet{'a'}) {
+		t.Errorf("Expected the bound TypeVariable to be renamed, so that a stays free. Got %v", got)
+	}
+	if s := fmt.Sprintf("%v", got); s != "μc. c → a" {
+		t.Errorf("Expected μc. c → a. Got %v", s)
+	}
+
+	n, err := NewFnType(b, r).Normalize(TypeVarSet{'b'}, TypeVarSet{'a'})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%v", n) != "a → μb. b → a" {
+		t.Errorf("Expected a → μb. b → a. Got %v", n)
+	}
+}
+
+func TestUnify_Recursive(t *testing.T) {
+	a, b, c := TypeVariable('a'), TypeVariable('b'), TypeVariable('c')
+	self := NewRecursiveType(a, NewFnType(a, Float))
+
+	sub, err := Unify(self, NewFnType(b, c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := sub.Get(b); !ok || !self.Eq(got) {
+		t.Errorf("Expected b to be %v. Got %v", self, got)
+	}
+	if got, ok := sub.Get(c); !ok || got != Float {
+		t.Errorf("Expected c to be Float. Got %v", got)
+	}
+
+	if _, err = Unify(self, NewRecursiveType(b, NewFnType(NewFnType(b, Float), Float))); err != nil {
+		t.Errorf("Expected μa. a → Float to unify with μb. (b → Float) → Float. Got %v", err)
+	}
+	if sub, err = Unify(self, NewRecursiveType(b, NewFnType(NewFnType(b, c), c))); err != nil {
+		t.Errorf("Expected μa. a → Float to unify with μb. (b → c) → c. Got %v", err)
+	} else if got, ok := sub.Get(c); !ok || got != Float {
+		t.Errorf("Expected c to be Float. Got %v", got)
+	}
+	if _, err = Unify(self, NewRecursiveType(b, NewFnType(b, Bool))); err == nil {
+		t.Errorf("Expected μa. a → Float to not unify with μb. b → Bool")
+	}
+
+	// without RecursiveTypes, the occurs check still applies
+	var te TypeError
+	if _, err = Unify(a, NewFnType(a, b)); !errors.As(err, &te) || te.Code() != CodeOccursCheck {
+		t.Errorf("Expected an occurs check error. Got %v", err)
+	}
+}
+
+func TestInfer_Recursive(t *testing.T) {
+	a := TypeVariable('a')
+	env := SimpleEnv{
+		"self": NewScheme(nil, NewRecursiveType(a, NewFnType(a, Float))),
+	}
+
+	// λx. x x
+	selfApply := λ{"x", app{lit("x"), lit("x")}}
+	// λf. (λx. f (x x)) (λx. f (x x))
+	fix := λ{"f", app{λ{"x", app{lit("f"), app{lit("x"), lit("x")}}}, λ{"x", app{lit("f"), app{lit("x"), lit("x")}}}}}
+
+	recursiveTests := []struct {
+		name string
+		expr Expression
+
+		correct string
+		err     bool // is an error without WithRecursiveTypes
+	}{
+		{"λx. x x", selfApply, "∀[a]: (μb. b → a) → a", true},
+		{"fix", fix, "∀[a]: (a → a) → a", true},
+		{"λo. o.get o", λ{"o", app{sel{lit("o"), "get"}, lit("o")}}, "∀[a, b]: (μc. {get: c → b | a}) → b", true},
+		{"self self", app{lit("self"), lit("self")}, "∀[]: Float", false},
+		{"λx. self x", λ{"x", app{lit("self"), lit("x")}}, "∀[]: (μa. a → Float) → Float", false},
+	}
+
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		for _, rts := range recursiveTests {
+			sch, err := Infer(env, rts.expr, opts...)
+			if !rts.err {
+				if err != nil {
+					t.Errorf("Test %q: %v", rts.name, err)
+				} else if got := fmt.Sprintf("%v", sch); got != rts.correct {
+					t.Errorf("Test %q: Expected %v. Got %v", rts.name, rts.correct, got)
+				}
+				continue
+			}
+			var te TypeError
+			if !errors.As(err, &te) || te.Code() != CodeOccursCheck {
+				t.Errorf("Test %q: Expected an occurs check error without WithRecursiveTypes. Got %v, %v", rts.name, sch, err)
+			}
+		}
+	}
+
+	for _, rts := range recursiveTests {
+		sch, err := Infer(env, rts.expr, WithRecursiveTypes())
+		if err != nil {
+			t.Errorf("Test %q: %v", rts.name, err)
+			continue
+		}
+		if got := fmt.Sprintf("%v", sch); got != rts.correct {
+			t.Errorf("Test %q: Expected %v. Got %v", rts.name, rts.correct, got)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package hm
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Field is a labelled field of a Row
+type Field struct {
+	Label string
+	Type  Type
+}
+
+// Row is the type of an extensible record. It has labelled fields, and an optional tail.
+// A Row without a tail is closed: it has exactly the fields listed. A Row with a TypeVariable tail is open:
+// the tail ranges over the rest of the fields. For example:
+//		{x: a | r}
+// is the type of any record that has a field x of type a.
+//
+// Labels are scoped, as in Leijen's "Extensible records with scoped labels": a Row may have many fields with the same label,
+// and the one that comes first shadows the others. The fields are kept sorted by label, and fields with the same label keep their order.
+type Row struct {
+	fields []Field
+	tail   Type
+}
+
+// NewRow creates a new Row. If the tail is itself a Row, its fields are merged into the new Row.
+func NewRow(tail Type, fields ...Field) *Row {
+	fs := make([]Field, len(fields))
+	copy(fs, fields)
+	sort.SliceStable(fs, func(i, j int) bool { return fs[i].Label < fs[j].Label })
+	return flattenRow(fs, tail)
+}
+
+// flattenRow creates a Row from sorted fields, merging the fields of the tail if the tail is a Row
+func flattenRow(fields []Field, tail Type) *Row {
+	tr, ok := tail.(*Row)
+	if !ok {
+		return &Row{fields: fields, tail: tail}
+	}
+	return &Row{fields: mergeFields(fields, tr.fields), tail: tr.tail}
+}
+
+// mergeFields merges two sorted lists of fields. Fields of a come before the fields of b with the same label.
+func mergeFields(a, b []Field) []Field {
+	if len(b) == 0 {
+		return a
+	}
+	retVal := make([]Field, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if b[j].Label < a[i].Label {
+			retVal = append(retVal, b[j])
+			j++
+			continue
+		}
+		retVal = append(retVal, a[i])
+		i++
+	}
+	retVal = append(retVal, a[i:]...)
+	return append(retVal, b[j:]...)
+}
+
+// Fields returns the fields of the row.
+func (t *Row) Fields() []Field { return t.fields }
+
+// Tail returns the tail of the row. It is nil if the row is closed.
+func (t *Row) Tail() Type { return t.tail }
+
+// Field returns the type of the first field with the given label.
+func (t *Row) Field(label string) (Type, bool) {
+	for _, f := range t.fields {
+		if f.Label == label {
+			return f.Type, true
+		}
+	}
+	return nil, false
+}
+
+func (t *Row) Name() string { return "Row" }
+
+func (t *Row) Apply(sub Subs) Substitutable {
+	if sub == nil {
+		return t
+	}
+	fields := make([]Field, len(t.fields))
+	for i, f := range t.fields {
+		fields[i] = Field{Label: f.Label, Type: f.Type.Apply(sub).(Type)}
+	}
+	var tail Type
+	if t.tail != nil {
+		tail = t.tail.Apply(sub).(Type)
+	}
+	return flattenRow(fields, tail)
+}
+
+func (t *Row) FreeTypeVar() TypeVarSet {
+	var tvs TypeVarSet
+	for _, f := range t.fields {
+		tvs = f.Type.FreeTypeVar().Union(tvs)
+	}
+	if t.tail != nil {
+		tvs = t.tail.FreeTypeVar().Union(tvs)
+	}
+	return tvs
+}
+
+func (t *Row) Normalize(k, v TypeVarSet) (Type, error) {
+	fields := make([]Field, len(t.fields))
+	var err error
+	for i, f := range t.fields {
+		fields[i].Label = f.Label
+		if fields[i].Type, err = f.Type.Normalize(k, v); err != nil {
+			return nil, err
+		}
+	}
+	var tail Type
+	if t.tail != nil {
+		if tail, err = t.tail.Normalize(k, v); err != nil {
+			return nil, err
+		}
+	}
+	return &Row{fields: fields, tail: tail}, nil
+}
+
+// Types returns the types of the fields, followed by the tail if there is one.
+func (t *Row) Types() Types {
+	n := len(t.fields)
+	if t.tail != nil {
+		n++
+	}
+	ts := BorrowTypes(n)
+	for i, f := range t.fields {
+		ts[i] = f.Type
+	}
+	if t.tail != nil {
+		ts[n-1] = t.tail
+	}
+	return ts
+}
+
+func (t *Row) Eq(other Type) bool {
+	ot, ok := expandAlias(other).(*Row)
+	if !ok || len(ot.fields) != len(t.fields) {
+		return false
+	}
+	for i, f := range t.fields {
+		if f.Label != ot.fields[i].Label || !f.Type.Eq(ot.fields[i].Type) {
+			return false
+		}
+	}
+	if t.tail == nil || ot.tail == nil {
+		return t.tail == nil && ot.tail == nil
+	}
+	return t.tail.Eq(ot.tail)
+}
+
+func (t *Row) Format(s fmt.State, c rune) {
+	s.Write([]byte("{"))
+	for i, f := range t.fields {
+		if i > 0 {
+			s.Write([]byte(", "))
+		}
+		fmt.Fprintf(s, "%s: %v", f.Label, f.Type)
+	}
+	switch {
+	case t.tail == nil:
+	case len(t.fields) == 0:
+		fmt.Fprintf(s, "| %v", t.tail)
+	default:
+		fmt.Fprintf(s, " | %v", t.tail)
+	}
+	s.Write([]byte("}"))
+}
+
+func (t *Row) String() string { return fmt.Sprintf("%v", t) }
+
+// Clone implements Cloner
+func (t *Row) Clone() interface{} {
+	fields := make([]Field, len(t.fields))
+	for i, f := range t.fields {
+		fields[i] = Field{Label: f.Label, Type: cloneType(f.Type)}
+	}
+	var tail Type
+	if t.tail != nil {
+		tail = cloneType(t.tail)
+	}
+	return &Row{fields: fields, tail: tail}
+}
+
+// unifyRows returns the pairs of types that have to be unified for the rows a and b to be unified.
+//
+// Fields are paired up by label: the nth field labelled l in a is paired with the nth field labelled l in b.
+// The fields that are left over have to be absorbed by the tail of the other row. If both rows have fields left over,
+// their tails are bound to the left over fields of the other row, with a fresh common tail from the Fresher.
+func unifyRows(f Fresher, a, b *Row) (as, bs Types, err error) {
+	var onlyA, onlyB []Field
+	i, j := 0, 0
+	for i < len(a.fields) || j < len(b.fields) {
+		switch {
+		case j == len(b.fields) || (i < len(a.fields) && a.fields[i].Label < b.fields[j].Label):
+			onlyA = append(onlyA, a.fields[i])
+			i++
+		case i == len(a.fields) || b.fields[j].Label < a.fields[i].Label:
+			onlyB = append(onlyB, b.fields[j])
+			j++
+		default:
+			as = append(as, a.fields[i].Type)
+			bs = append(bs, b.fields[j].Type)
+			i++
+			j++
+		}
+	}
+
+	switch {
+	case len(onlyA) == 0 && len(onlyB) == 0:
+		if a.tail == nil && b.tail == nil {
+			break
+		}
+		as = append(as, rowTail(a))
+		bs = append(bs, rowTail(b))
+	case len(onlyA) == 0:
+		if a.tail == nil {
+			return nil, nil, MissingFieldError{onlyB[0].Label, a}
+		}
+		if b.tail != nil && a.tail.Eq(b.tail) {
+			return nil, nil, UnificationError{a, b}
+		}
+		as = append(as, a.tail)
+		bs = append(bs, &Row{fields: onlyB, tail: b.tail})
+	case len(onlyB) == 0:
+		if b.tail == nil {
+			return nil, nil, MissingFieldError{onlyA[0].Label, b}
+		}
+		if a.tail != nil && a.tail.Eq(b.tail) {
+			return nil, nil, UnificationError{a, b}
+		}
+		as = append(as, &Row{fields: onlyA, tail: a.tail})
+		bs = append(bs, b.tail)
+	default:
+		if a.tail == nil {
+			return nil, nil, MissingFieldError{onlyB[0].Label, a}
+		}
+		if b.tail == nil {
+			return nil, nil, MissingFieldError{onlyA[0].Label, b}
+		}
+		if a.tail.Eq(b.tail) {
+			// {x: a | r} ~ {y: b | r} has no solution
+			return nil, nil, UnificationError{a, b}
+		}
+		tail := f.Fresh()
+		as = append(as, a.tail, b.tail)
+		bs = append(bs, &Row{fields: onlyB, tail: tail}, &Row{fields: onlyA, tail: tail})
+	}
+	return
+}
+
+// rowTail returns the tail of the row. The tail of a closed row is the empty row.
+func rowTail(r *Row) Type {
+	if r.tail == nil {
+		return &Row{}
+	}
+	return r.tail
+}
@@ -0,0 +1,193 @@
+package hm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestRowBasics(t *testing.T) {
+	r := NewRow(TypeVariable('r'), Field{"y", neutron}, Field{"x", TypeVariable('a')}, Field{"x", proton})
+	if fmt.Sprintf("%v", r) != "{x: a, x: proton, y: neutron | r}" {
+		t.Errorf("Expected the fields to be sorted by label. Got %v", r)
+	}
+	if ft, ok := r.Field("x"); !ok || ft != TypeVariable('a') {
+		t.Errorf("Expected the first field labelled x to be a. Got %v", ft)
+	}
+	if _, ok := r.Field("z"); ok {
+		t.Error("Did not expect a field labelled z")
+	}
+
+	ftv := r.FreeTypeVar()
+	if !ftv.Equals(TypeVarSet{'a', 'r'}) {
+		t.Errorf("Expected ftv: {a, r}. Got %v instead", ftv)
+	}
+
+	// substituting the tail with a row flattens the row. The fields of the tail are shadowed.
+	r2 := r.Apply(mSubs{'r': NewRow(nil, Field{"x", electron}, Field{"w", muon})}).(*Row)
+	if fmt.Sprintf("%v", r2) != "{w: muon, x: a, x: proton, x: electron, y: neutron}" {
+		t.Errorf("Expected a flattened, closed row. Got %v", r2)
+	}
+	if r2.Tail() != nil {
+		t.Errorf("Expected a closed row. Got tail %v", r2.Tail())
+	}
+	if !r.Eq(NewRow(TypeVariable('r'), Field{"x", TypeVariable('a')}, Field{"x", proton}, Field{"y", neutron})) {
+		t.Error("Apply should not change the original row")
+	}
+	if r.Eq(NewRow(TypeVariable('r'), Field{"x", proton}, Field{"x", TypeVariable('a')}, Field{"y", neutron})) {
+		t.Error("The order of fields with the same label matters")
+	}
+	if r.Eq(NewRow(nil, Field{"x", TypeVariable('a')}, Field{"x", proton}, Field{"y", neutron})) {
+		t.Error("Closed rows are not equal to open rows")
+	}
+
+	if fmt.Sprintf("%v", NewRow(nil)) != "{}" {
+		t.Errorf("Wrong format of the empty row: %v", NewRow(nil))
+	}
+	if fmt.Sprintf("%v", NewRow(TypeVariable('r'))) != "{| r}" {
+		t.Errorf("Wrong format of the empty open row: %v", NewRow(TypeVariable('r')))
+	}
+
+	n, err := r.Normalize(TypeVarSet{'a', 'r'}, TypeVarSet{'a', 'b'})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%v", n) != "{x: a, x: proton, y: neutron | b}" {
+		t.Errorf("Normalize failed. Got %v", n)
+	}
+}
+
+var unifyRowTests = []struct {
+	name string
+	a, b Type
+
+	correct mSubs // the expected types of the type variables after unification
+	err     ErrorCode
+}{
+	{"{x: a | r} ~ {x: proton}", NewRow(TypeVariable('r'), Field{"x", TypeVariable('a')}), NewRow(nil, Field{"x", proton}),
+		mSubs{'a': proton, 'r': NewRow(nil)}, 0},
+	{"{x: a | r} ~ {y: neutron, x: proton}", NewRow(TypeVariable('r'), Field{"x", TypeVariable('a')}), NewRow(nil, Field{"y", neutron}, Field{"x", proton}),
+		mSubs{'a': proton, 'r': NewRow(nil, Field{"y", neutron})}, 0},
+	{"{x: a, y: b} ~ {y: neutron, x: proton}", NewRow(nil, Field{"x", TypeVariable('a')}, Field{"y", TypeVariable('b')}), NewRow(nil, Field{"y", neutron}, Field{"x", proton}),
+		mSubs{'a': proton, 'b': neutron}, 0},
+	// the common tail is the first TypeVariable that is not in the rows
+	{"{x: a | r} ~ {y: b | s}", NewRow(TypeVariable('r'), Field{"x", TypeVariable('a')}), NewRow(TypeVariable('s'), Field{"y", TypeVariable('b')}),
+		mSubs{'r': NewRow(TypeVariable('c'), Field{"y", TypeVariable('b')}), 's': NewRow(TypeVariable('c'), Field{"x", TypeVariable('a')})}, 0},
+	{"{x: a | r} ~ {x: b, x: proton | r}", NewRow(TypeVariable('r'), Field{"x", TypeVariable('a')}), NewRow(TypeVariable('s'), Field{"x", TypeVariable('b')}, Field{"x", proton}),
+		mSubs{'a': TypeVariable('b'), 'r': NewRow(TypeVariable('s'), Field{"x", proton})}, 0},
+
+	{"{x: a} ~ {y: b}", NewRow(nil, Field{"x", TypeVariable('a')}), NewRow(nil, Field{"y", TypeVariable('b')}), nil, CodeMissingField},
+	{"{x: a} ~ {x: a, y: b}", NewRow(nil, Field{"x", TypeVariable('a')}), NewRow(nil, Field{"x", TypeVariable('a')}, Field{"y", TypeVariable('b')}), nil, CodeMissingField},
+	{"{x: proton | r} ~ {x: neutron | r}", NewRow(TypeVariable('r'), Field{"x", proton}), NewRow(TypeVariable('r'), Field{"x", neutron}), nil, CodeUnification},
+	{"{x: a | r} ~ {y: b | r}", NewRow(TypeVariable('r'), Field{"x", TypeVariable('a')}), NewRow(TypeVariable('r'), Field{"y", TypeVariable('b')}), nil, CodeUnification},
+	{"{x: a | r} ~ r", NewRow(TypeVariable('r'), Field{"x", TypeVariable('a')}), TypeVariable('r'), nil, CodeOccursCheck},
+	{"{x: a} ~ proton", NewRow(nil, Field{"x", TypeVariable('a')}), proton, nil, CodeUnification},
+}
+
+func TestUnify_Rows(t *testing.T) {
+	for _, uts := range unifyRowTests {
+		for _, engine := range []string{"Unify", "UnionFind"} {
+			var sub Subs
+			var err error
+			if engine == "Unify" {
+				sub, err = Unify(uts.a, uts.b)
+			} else {
+				s := newUFSolver()
+				s.solve(Constraints{{a: uts.a, b: uts.b}})
+				sub, err = s.sub, s.err
+			}
+
+			if uts.err != 0 {
+				var te TypeError
+				if !errors.As(err, &te) || te.Code() != uts.err {
+					t.Errorf("%s %q: Expected a %v error. Got %v", engine, uts.name, uts.err, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s %q: %v", engine, uts.name, err)
+				continue
+			}
+
+			// both sides are equal after the substitution
+			a := cloneType(uts.a).Apply(sub).(Type)
+			b := cloneType(uts.b).Apply(sub).(Type)
+			if !a.Eq(b) {
+				t.Errorf("%s %q: Expected both sides to be equal after unification. Got %v and %v", engine, uts.name, a, b)
+			}
+			for tv, T := range uts.correct {
+				if got := tv.Apply(sub).(Type); !got.Eq(T) {
+					t.Errorf("%s %q: Expected %v to be %v. Got %v", engine, uts.name, tv, T, got)
+				}
+			}
+		}
+	}
+}
+
+func TestInfer_Rows(t *testing.T) {
+	a, b := TypeVariable('a'), TypeVariable('b')
+	env := SimpleEnv{
+		"pair": NewScheme(TypeVarSet{'a', 'b'}, NewFnType(a, b, NewRecordType("", a, b))),
+	}
+	pair := func(x, y Expression) Expression { return app{app{lit("pair"), x}, y} }
+	rec := func(fields ...interface{}) Expression {
+		var r Expression = emptyRec{}
+		for i := 0; i < len(fields); i += 2 {
+			r = ext{r, fields[i].(string), fields[i+1].(Expression)}
+		}
+		return r
+	}
+
+	inferRowTests := []struct {
+		name string
+		expr Expression
+
+		correct string
+		err     ErrorCode
+	}{
+		{"λr. r.x", λ{"r", sel{lit("r"), "x"}}, "∀[a, b]: {x: a | b} → a", 0},
+		{"λr. {y = 1 | r}", λ{"r", ext{lit("r"), "y", lit("1")}}, "∀[a]: {| a} → {y: Float | a}", 0},
+		{"λr. r - x", λ{"r", restr{lit("r"), "x"}}, "∀[a, b]: {x: a | b} → {| b}", 0},
+		{"{x = 1, y = true}", rec("x", lit("1"), "y", lit("true")), "∀[]: {x: Float, y: Bool}", 0},
+		{"(λr. r.x) {x = 1}", app{λ{"r", sel{lit("r"), "x"}}, rec("x", lit("1"))}, "∀[]: Float", 0},
+		{"(λr. r.x) {y = 1}", app{λ{"r", sel{lit("r"), "x"}}, rec("y", lit("1"))}, "", CodeMissingField},
+		{"λr. pair r.x r.y", λ{"r", pair(sel{lit("r"), "x"}, sel{lit("r"), "y"})}, "∀[a, b, c]: {x: a, y: b | c} → (a, b)", 0},
+		{"1.x", sel{lit("1"), "x"}, "", CodeUnification},
+
+		// scoped labels
+		{"{x = true | {x = 1}}.x", sel{rec("x", lit("1"), "x", lit("true")), "x"}, "∀[]: Bool", 0},
+		{"({x = true | {x = 1}} - x).x", sel{restr{rec("x", lit("1"), "x", lit("true")), "x"}, "x"}, "∀[]: Float", 0},
+		{"λr. (r - x).x", λ{"r", sel{restr{lit("r"), "x"}, "x"}}, "∀[a, b, c]: {x: a, x: b | c} → b", 0},
+
+		// polymorphism
+		{"let getX = λr. r.x in pair (getX {x = 1}) (getX {y = 1, x = true})",
+			let{"getX", λ{"r", sel{lit("r"), "x"}}, pair(app{lit("getX"), rec("x", lit("1"))}, app{lit("getX"), rec("y", lit("1"), "x", lit("true"))})},
+			"∀[]: (Float, Bool)", 0},
+
+		// the tail that is generated when solving the definition of f is shared with r
+		{"λr. let f = λu. (λv. r - z) (pair r.x r.y) in (f 1).w",
+			λ{"r", let{"f", λ{"u", app{λ{"v", restr{lit("r"), "z"}}, pair(sel{lit("r"), "x"}, sel{lit("r"), "y"})}}, sel{app{lit("f"), lit("1")}, "w"}}},
+			"∀[a, b, c, d, e]: {w: d, x: b, y: c, z: a | e} → d", 0},
+	}
+
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		for _, its := range inferRowTests {
+			sch, err := Infer(env, its.expr, opts...)
+			if its.err != 0 {
+				var te TypeError
+				if !errors.As(err, &te) || te.Code() != its.err {
+					t.Errorf("Test %q: Expected a %v error. Got %v, %v", its.name, its.err, sch, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("Test %q: %+v", its.name, err)
+				continue
+			}
+			if got := fmt.Sprintf("%v", sch); got != its.correct {
+				t.Errorf("Test %q: Expected %v. Got %v", its.name, its.correct, got)
+			}
+		}
+	}
+}
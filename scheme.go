@@ -6,8 +6,12 @@ import "fmt"
 // It basically says this:
 //		∀TypeVariables.Type.
 // What this means is for all TypeVariables enclosed in Type, those TypeVariables can be of any Type.
+//
+// A Scheme may also be qualified by predicates, which restrict the types the TypeVariables can be:
+//		∀TypeVariables. Predicates ⇒ Type
 type Scheme struct {
 	tvs TypeVarSet
+	ps  Predicates
 	t   Type
 }
 
@@ -18,6 +22,15 @@ func NewScheme(tvs TypeVarSet, t Type) *Scheme {
 	}
 }
 
+// NewQualifiedScheme creates a new Scheme that is qualified by the predicates
+func NewQualifiedScheme(tvs TypeVarSet, ps Predicates, t Type) *Scheme {
+	return &Scheme{
+		tvs: tvs,
+		ps:  ps,
+		t:   t,
+	}
+}
+
 func (s *Scheme) Apply(sub Subs) Substitutable {
 	logf("s: %v, sub: %v", s, sub)
 	if sub == nil {
@@ -31,11 +44,15 @@ func (s *Scheme) Apply(sub Subs) Substitutable {
 	}
 
 	s.t = s.t.Apply(sub).(Type)
+	s.ps = s.ps.Apply(sub).(Predicates)
 	return s
 }
 
 func (s *Scheme) FreeTypeVar() TypeVarSet {
 	ftvs := s.t.FreeTypeVar()
+	if len(s.ps) > 0 {
+		ftvs = s.ps.FreeTypeVar().Union(ftvs)
+	}
 	tvs := s.tvs.Set()
 	return ftvs.Difference(tvs)
 }
@@ -45,8 +62,14 @@ func (s *Scheme) Clone() *Scheme {
 	for i, v := range s.tvs {
 		tvs[i] = v
 	}
+	var ps Predicates
+	if len(s.ps) > 0 {
+		ps = make(Predicates, len(s.ps))
+		copy(ps, s.ps)
+	}
 	return &Scheme{
 		tvs: tvs,
+		ps:  ps,
 		t:   s.t,
 	}
 }
@@ -60,9 +83,16 @@ func (s *Scheme) Format(state fmt.State, c rune) {
 			fmt.Fprintf(state, "%v", tv)
 		}
 	}
-	fmt.Fprintf(state, "]: %v", s.t)
+	state.Write([]byte("]: "))
+	if len(s.ps) > 0 {
+		fmt.Fprintf(state, "%v ⇒ ", s.ps)
+	}
+	fmt.Fprintf(state, "%v", s.t)
 }
 
+// Predicates returns the predicates that qualify the scheme
+func (s *Scheme) Predicates() Predicates { return s.ps }
+
 // Type returns the type of the scheme, as well as a boolean indicating if *Scheme represents a monotype. If it's a polytype, it'll return false
 func (s *Scheme) Type() (t Type, isMonoType bool) {
 	if len(s.tvs) == 0 {
@@ -76,16 +106,26 @@ func (s *Scheme) Normalize() (err error) {
 	tfv := s.t.FreeTypeVar()
 
 	if len(tfv) == 0 {
-		return nil
+		// the TypeVariables bound by RecursiveTypes (and ForallTypes) are still named
+		s.t, err = s.t.Normalize(nil, nil)
+		return
 	}
 
 	defer ReturnTypeVarSet(tfv)
+
 	ord := BorrowTypeVarSet(len(tfv))
 	for i := range tfv {
-		ord[i] = TypeVariable(letters[i])
+		ord[i] = NewTypeVar(i)
 	}
 
-	s.t, err = s.t.Normalize(tfv, ord)
+	if s.t, err = s.t.Normalize(tfv, ord); err != nil {
+		return
+	}
+	for i, p := range s.ps {
+		if s.ps[i], err = p.Normalize(tfv, ord); err != nil {
+			return
+		}
+	}
 	s.tvs = ord.Set()
 	return
 }
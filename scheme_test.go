@@ -73,3 +73,60 @@ func TestSchemeNormalize(t *testing.T) {
 		t.Errorf("Expected: TypeVarSet{'a','b'}. Got: %v", s.tvs)
 	}
 }
+
+func TestSchemeNormalize_Large(t *testing.T) {
+	// a scheme with more type variables than there are letters
+	ts := make(Types, 300)
+	tvs := make(TypeVarSet, 300)
+	for i := range ts {
+		tv := TypeVariable('α' + i)
+		ts[i] = tv
+		tvs[i] = tv
+	}
+
+	s := NewScheme(tvs, NewFnType(ts...))
+	if err := s.Normalize(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.tvs) != 300 {
+		t.Fatalf("Expected 300 type variables. Got %d instead", len(s.tvs))
+	}
+
+	for i, tv := range s.tvs {
+		if tv != NewTypeVar(i) {
+			t.Errorf("Expected type variable %d to be %v. Got %v instead", i, NewTypeVar(i), tv)
+			break
+		}
+	}
+
+	if last := s.t.(*FunctionType).Ret(true); last != NewTypeVar(299) {
+		t.Errorf("Expected the final return type to be %v. Got %v instead", NewTypeVar(299), last)
+	}
+}
+
+func TestQualifiedScheme(t *testing.T) {
+	s := NewQualifiedScheme(TypeVarSet{'b'}, Predicates{NewPredicate("Num", TypeVariable('b'))}, NewFnType(TypeVariable('b'), TypeVariable('b'), TypeVariable('c')))
+	if fmt.Sprintf("%v", s) != "∀[b]: Num b ⇒ b → b → c" {
+		t.Errorf("Scheme format is wrong. Got %q", fmt.Sprintf("%v", s))
+	}
+
+	ftv := s.FreeTypeVar()
+	if !ftv.Equals(TypeVarSet{'c'}) {
+		t.Errorf("Expected ftv: {'c'}. Got %v instead", ftv)
+	}
+
+	if err := s.Normalize(); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%v", s) != "∀[a, b]: Num a ⇒ a → a → b" {
+		t.Errorf("Normalized scheme is wrong. Got %q", fmt.Sprintf("%v", s))
+	}
+
+	// the predicates of the bound type variables are not substituted
+	s = NewQualifiedScheme(TypeVarSet{'a'}, Predicates{NewPredicate("Num", TypeVariable('a')), NewPredicate("Eq", TypeVariable('b'))}, NewFnType(TypeVariable('a'), TypeVariable('b')))
+	s.Apply(mSubs{'a': proton, 'b': neutron})
+	if !s.Predicates().Contains(NewPredicate("Num", TypeVariable('a'))) || !s.Predicates().Contains(NewPredicate("Eq", neutron)) {
+		t.Errorf("Apply failed. Got %v", s)
+	}
+}
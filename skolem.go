@@ -0,0 +1,122 @@
+package hm
+
+import "fmt"
+
+// Skolem is a rigid type variable: it stands for a type that is unknown, but fixed. A Skolem only unifies with itself and with TypeVariables.
+//
+// Skolems replace the quantified TypeVariables of an annotation while the annotated expression is checked.
+// This is how an expression of type a → Float is found to be less polymorphic than the annotation ∀[a]: a → a:
+// the Skolem that replaces a cannot be Float.
+type Skolem struct {
+	tv TypeVariable // the TypeVariable of the annotation that is replaced
+	id TypeVariable // a fresh TypeVariable, which tells the Skolems of the same TypeVariable apart
+}
+
+func (t Skolem) Name() string                            { return t.tv.Name() }
+func (t Skolem) Apply(Subs) Substitutable                { return t }
+func (t Skolem) FreeTypeVar() TypeVarSet                 { return nil }
+func (t Skolem) Normalize(k, v TypeVarSet) (Type, error) { return t, nil }
+func (t Skolem) Types() Types                            { return nil }
+func (t Skolem) String() string                          { return fmt.Sprintf("%v", t) }
+func (t Skolem) Format(s fmt.State, c rune)              { fmt.Fprintf(s, "%v", t.tv) }
+func (t Skolem) Eq(other Type) bool                      { return expandAlias(other) == t }
+
+// TypeVar returns the TypeVariable of the annotation that the Skolem replaces
+func (t Skolem) TypeVar() TypeVariable { return t.tv }
+
+// freshSkolem returns a fresh Skolem that replaces the TypeVariable. It's made unique by a fresh TypeVariable from the Fresher.
+func freshSkolem(f Fresher, tv TypeVariable) Skolem {
+	return Skolem{tv: tv, id: f.Fresh()}
+}
+
+// rigid returns an error if either of the types is a Skolem. It's called when the types are known to be different, and neither is a TypeVariable.
+func rigid(a, b Type) error {
+	if sk, ok := a.(Skolem); ok {
+		return RigidTypeVariableError{sk, b}
+	}
+	if sk, ok := b.(Skolem); ok {
+		return RigidTypeVariableError{sk, a}
+	}
+	return nil
+}
+
+// closeScheme returns a scheme like s, with the TypeVariables that are free in s quantified as well.
+// This is how the TypeVariables of an annotation are quantified implicitly.
+func closeScheme(s *Scheme) *Scheme {
+	tvs := s.t.FreeTypeVar().Union(append(TypeVarSet(nil), s.tvs...))
+	if len(s.ps) > 0 {
+		tvs = s.ps.FreeTypeVar().Union(tvs)
+	}
+	return &Scheme{tvs: tvs, ps: s.ps, t: s.t}
+}
+
+// skolemize replaces the quantified TypeVariables of the scheme with fresh Skolems.
+func (infer *inferer) skolemize(s *Scheme) (Type, Predicates, []Skolem) {
+	sub := make(mSubs, len(s.tvs))
+	sks := make([]Skolem, len(s.tvs))
+	for i, tv := range s.tvs {
+		sks[i] = freshSkolem(infer, tv)
+		sub[tv] = sks[i]
+	}
+	return cloneType(s.t).Apply(sub).(Type), s.ps.Apply(sub).(Predicates), sks
+}
+
+// mentions returns the first of the Skolems that occurs in t
+func mentions(t Type, sks []Skolem) (Skolem, bool) {
+	if sk, ok := t.(Skolem); ok {
+		for _, s := range sks {
+			if s == sk {
+				return sk, true
+			}
+		}
+		return Skolem{}, false
+	}
+	for _, c := range t.Types() {
+		if sk, ok := mentions(c, sks); ok {
+			return sk, true
+		}
+	}
+	return Skolem{}, false
+}
+
+// checkSkolems checks the Skolems of an annotation, after the constraints of the annotated expression have been generated one level deeper than the current level.
+//
+// The constraints are solved eagerly, to check that the Skolems don't escape into the types of the enclosing expressions,
+// and that the predicates on the Skolems (the ones collected since start) follow from the given predicates of the annotation.
+func (infer *inferer) checkSkolems(sks []Skolem, given Predicates, start int) error {
+	sub, err := infer.solution()
+	if err != nil {
+		return err
+	}
+	if sub != nil {
+		for _, s := range sub.Iter() {
+			if infer.levels[s.Tv] > infer.level {
+				continue
+			}
+			if sk, ok := mentions(s.T, sks); ok {
+				return SkolemEscapeError{Skolem: sk, Type: s.T}
+			}
+		}
+	}
+
+	// the rest of the predicates are left for the enclosing expressions
+	var rest Predicates
+	for _, p := range infer.ps[start:] {
+		var onSkolem bool
+		applied := p.Apply(sub).(Predicate)
+		for _, pt := range applied.Types {
+			if _, ok := mentions(pt, sks); ok {
+				onSkolem = true
+				break
+			}
+		}
+		switch {
+		case !onSkolem:
+			rest = append(rest, p)
+		case !infer.classEnv().Entails(given, applied):
+			return NoInstanceError{applied}
+		}
+	}
+	infer.ps = append(infer.ps[:start], rest...)
+	return nil
+}
@@ -0,0 +1,97 @@
+package hm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestSkolem(t *testing.T) {
+	a, b := Skolem{tv: 'a', id: 1}, Skolem{tv: 'a', id: 2}
+	if !a.Eq(a) || a.Eq(b) || a.Eq(TypeVariable('a')) {
+		t.Errorf("Skolems are only equal to themselves")
+	}
+	if got := fmt.Sprintf("%v", NewFnType(a, proton)); got != "a → proton" {
+		t.Errorf("Expected a → proton. Got %v", got)
+	}
+
+	if sub, err := Unify(a, TypeVariable('b')); err != nil || sub == nil {
+		t.Errorf("A Skolem unifies with a TypeVariable. Got %v, %v", sub, err)
+	}
+	var re RigidTypeVariableError
+	if _, err := Unify(proton, a); !errors.As(err, &re) || re.Skolem != a || re.Type != proton {
+		t.Errorf("Expected a RigidTypeVariableError. Got %v", err)
+	}
+	if _, err := Unify(a, b); !errors.As(err, &re) {
+		t.Errorf("Expected a RigidTypeVariableError. Got %v", err)
+	}
+
+	// the Skolems of Eq are told apart from the Skolems in the types
+	f := newTypesFresher(a, b)
+	if sk := freshSkolem(f, 'a'); sk.Eq(a) || sk.Eq(b) {
+		t.Errorf("Expected a fresh Skolem. Got %v", sk.id)
+	}
+	fa := NewForallType(TypeVarSet{'c'}, NewFnType(TypeVariable('c'), a))
+	fb := NewForallType(TypeVarSet{'c'}, NewFnType(TypeVariable('c'), b))
+	if !fa.Eq(fa) || fa.Eq(fb) {
+		t.Errorf("Expected %v to only be equal to itself", fa)
+	}
+}
+
+func TestInfer_Annotated(t *testing.T) {
+	a, b := TypeVariable('a'), TypeVariable('b')
+	env := SimpleEnv{
+		"+":    NewQualifiedScheme(TypeVarSet{'a'}, Predicates{NewPredicate("Num", a)}, NewFnType(a, a, a)),
+		"plus": NewScheme(nil, NewFnType(Float, Float, Float)),
+	}
+	idAnn := NewScheme(TypeVarSet{'a'}, NewFnType(a, a))
+
+	annotatedTests := []struct {
+		name string
+		expr Expression
+
+		correct string
+		err     ErrorCode
+	}{
+		{"(λx. x : ∀a. a → a)", annotated{λ{"x", lit("x")}, idAnn}, "∀[a]: a → a", 0},
+		{"(λx. x : Float → Float)", annotated{λ{"x", lit("x")}, NewScheme(nil, NewFnType(Float, Float))}, "∀[]: Float → Float", 0},
+		{"(λx. plus x 1 : a → a)", annotated{λ{"x", app{app{lit("plus"), lit("x")}, lit("1")}}, NewScheme(nil, NewFnType(a, a))}, "", CodeRigidTypeVariable},
+		{"(λx. x : a → b)", annotated{λ{"x", lit("x")}, NewScheme(nil, NewFnType(a, b))}, "", CodeRigidTypeVariable},
+		{"λy. (λx. y : a → a)", λ{"y", annotated{λ{"x", lit("y")}, idAnn}}, "", CodeSkolemEscape},
+		{"let id = (λx. x : a → a) in (id 1, id true)",
+			let{"id", annotated{λ{"x", lit("x")}, idAnn}, tuple{app{lit("id"), lit("1")}, app{lit("id"), lit("true")}}},
+			"∀[]: (Float, Bool)", 0},
+		{"(λx. + x x : ∀a. Num a ⇒ a → a)",
+			annotated{λ{"x", app{app{lit("+"), lit("x")}, lit("x")}}, NewQualifiedScheme(TypeVarSet{'a'}, Predicates{NewPredicate("Num", a)}, NewFnType(a, a))},
+			"∀[a]: Num a ⇒ a → a", 0},
+		{"(λx. + x x : ∀a. a → a)", annotated{λ{"x", app{app{lit("+"), lit("x")}, lit("x")}}, idAnn}, "", CodeNoInstance},
+
+		// annotated parameters
+		{"λ(x : Float). x", typedλ{"x", Float, lit("x")}, "∀[]: Float → Float", 0},
+		{"λ(x : a). plus x 1", typedλ{"x", a, app{app{lit("plus"), lit("x")}, lit("1")}}, "∀[]: Float → Float", 0},
+		{"λ(x : Bool). plus x 1", typedλ{"x", Bool, app{app{lit("plus"), lit("x")}, lit("1")}}, "", CodeUnification},
+		{"(λ(x : a). x : a → a)", annotated{typedλ{"x", a, lit("x")}, idAnn}, "∀[a]: a → a", 0},
+	}
+
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		opts = append(opts, WithClasses(numClasses()))
+		for _, ats := range annotatedTests {
+			sch, err := Infer(env, ats.expr, opts...)
+			if ats.err != 0 {
+				var te TypeError
+				if !errors.As(err, &te) || te.Code() != ats.err {
+					t.Errorf("Test %q: Expected a %v error. Got %v, %v", ats.name, ats.err, sch, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("Test %q: %+v", ats.name, err)
+				continue
+			}
+			if got := fmt.Sprintf("%v", sch); got != ats.correct {
+				t.Errorf("Test %q: Expected %v. Got %v", ats.name, ats.correct, got)
+			}
+		}
+	}
+}
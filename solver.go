@@ -1,8 +1,9 @@
 package hm
 
 type solver struct {
-	sub Subs
-	err error
+	sub     Subs
+	err     error
+	fresher Fresher // generates the TypeVariables of the tails of rows
 }
 
 func newSolver() *solver {
@@ -17,6 +18,9 @@ func (s *solver) solve(cs Constraints) {
 	if s.err != nil {
 		return
 	}
+	if s.fresher == nil {
+		s.fresher = newTypesFresher(cs.types()...)
+	}
 
 	switch len(cs) {
 	case 0:
@@ -24,7 +28,15 @@ func (s *solver) solve(cs Constraints) {
 	default:
 		var sub Subs
 		c := cs[0]
-		sub, s.err = Unify(c.a, c.b)
+		if c.a.Eq(c.b) {
+			// already satisfied. This happens when constraints are solved more than once.
+			s.solve(cs[1:])
+			return
+		}
+		if sub, s.err = unify(s.fresher, c.a, c.b); s.err != nil {
+			s.err = c.fail(c.a, c.b, s.err)
+			return
+		}
 		defer ReturnSubs(s.sub)
 
 		s.sub = compose(sub, s.sub)
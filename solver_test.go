@@ -8,14 +8,14 @@ var solverTest = []struct {
 	expected Subs
 	err      bool
 }{
-	{Constraints{{TypeVariable('a'), proton}}, mSubs{'a': proton}, false},
-	{Constraints{{NewFnType(TypeVariable('a'), proton), neutron}}, nil, true},
-	{Constraints{{NewFnType(TypeVariable('a'), proton), NewFnType(proton, proton)}}, mSubs{'a': proton}, false},
+	{Constraints{{a: TypeVariable('a'), b: proton}}, mSubs{'a': proton}, false},
+	{Constraints{{a: NewFnType(TypeVariable('a'), proton), b: neutron}}, nil, true},
+	{Constraints{{a: NewFnType(TypeVariable('a'), proton), b: NewFnType(proton, proton)}}, mSubs{'a': proton}, false},
 
 	{Constraints{
 		{
-			NewFnType(TypeVariable('a'), TypeVariable('a'), list{TypeVariable('a')}),
-			NewFnType(proton, proton, TypeVariable('b')),
+			a: NewFnType(TypeVariable('a'), TypeVariable('a'), list{TypeVariable('a')}),
+			b: NewFnType(proton, proton, TypeVariable('b')),
 		},
 	},
 		mSubs{'a': proton, 'b': list{proton}}, false,
@@ -23,8 +23,8 @@ var solverTest = []struct {
 
 	{
 		Constraints{
-			{TypeVariable('a'), TypeVariable('b')},
-			{TypeVariable('a'), proton},
+			{a: TypeVariable('a'), b: TypeVariable('b')},
+			{a: TypeVariable('a'), b: proton},
 		},
 		mSubs{'a': proton}, false,
 	},
@@ -32,8 +32,8 @@ var solverTest = []struct {
 	{
 		Constraints{
 			{
-				NewRecordType("", TypeVariable('a'), TypeVariable('a'), TypeVariable('b')),
-				NewRecordType("", neutron, neutron, proton),
+				a: NewRecordType("", TypeVariable('a'), TypeVariable('a'), TypeVariable('b')),
+				b: NewRecordType("", neutron, neutron, proton),
 			},
 		},
 		mSubs{'a': neutron, 'b': proton}, false,
@@ -24,6 +24,24 @@ func (cs Constraints) Apply(sub Subs) Substitutable {
 	return cs
 }
 
+// types returns the types of both sides of every constraint
+func (cs Constraints) types() Types {
+	retVal := make(Types, 0, 2*len(cs))
+	for _, c := range cs {
+		retVal = append(retVal, c.a, c.b)
+	}
+	return retVal
+}
+
+// Clone returns a deep copy of the constraints. Solving constraints mutates the types in them, so solving a clone leaves the original constraints intact.
+func (cs Constraints) Clone() Constraints {
+	retVal := make(Constraints, len(cs))
+	for i, c := range cs {
+		retVal[i] = Constraint{cloneType(c.a), cloneType(c.b), c.origin}
+	}
+	return retVal
+}
+
 func (cs Constraints) FreeTypeVar() TypeVarSet {
 	var retVal TypeVarSet
 	for _, v := range cs {
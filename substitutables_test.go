@@ -7,8 +7,8 @@ import (
 
 func TestConstraints(t *testing.T) {
 	cs := Constraints{
-		{TypeVariable('a'), proton},
-		{TypeVariable('b'), proton},
+		{a: TypeVariable('a'), b: proton},
+		{a: TypeVariable('b'), b: proton},
 	}
 	correct := TypeVarSet{'a', 'b'}
 
@@ -116,6 +116,25 @@ func (s mSubs) Clone() Subs {
 	return retVal
 }
 
+// extend is like compose(next, sub), except that sub is left as it is: the types it substitutes are cloned before next is applied to them.
+// It's how a solution is extended with the solution of the constraints that come after.
+func extend(sub, next Subs) Subs {
+	if sub == nil {
+		return next
+	}
+	retVal := sub.Clone()
+	if next == nil {
+		return retVal
+	}
+	for _, v := range retVal.Iter() {
+		retVal = retVal.Add(v.Tv, cloneType(v.T).Apply(next).(Type))
+	}
+	for _, v := range next.Iter() {
+		retVal = retVal.Add(v.Tv, v.T)
+	}
+	return retVal
+}
+
 func compose(a, b Subs) (retVal Subs) {
 	if b == nil {
 		return a
@@ -142,3 +142,21 @@ func TestCompose(t *testing.T) {
 		}
 	}
 }
+
+func TestExtend(t *testing.T) {
+	b := TypeVariable('b')
+	fn := NewFnType(b, b)
+	sub := mSubs{'a': fn}
+	got := extend(sub, mSubs{'b': proton})
+
+	if T, ok := got.Get('a'); !ok || !T.Eq(NewFnType(proton, proton)) {
+		t.Errorf("Expected a to be replaced by proton → proton. Got %v", T)
+	}
+	if T, ok := got.Get('b'); !ok || T != proton {
+		t.Errorf("Expected b to be replaced by proton. Got %v", T)
+	}
+	// the extended substitution is left as it is
+	if T, _ := sub.Get('a'); T != fn || !fn.Eq(NewFnType(b, b)) {
+		t.Errorf("Expected the substitution that is extended to be left as it is. Got %v", sub)
+	}
+}
@@ -0,0 +1,512 @@
+package hm
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// SubtypeClass is the class of the Predicates that are subtype constraints. A Scheme whose TypeVariables are bounded has them as its Predicates:
+//		∀[a]: Int <: a ⇒ a → a
+// is the type of the functions from any supertype of Int to itself. The Predicate Int <: a is NewPredicate(SubtypeClass, Int, a).
+const SubtypeClass = "<:"
+
+// Lattice is a partial order of TypeConsts, declared by the user. Int <: Float says that an Int may be used wherever a Float is expected.
+//
+// The order is reflexive and transitive, so every TypeConst is a subtype of itself, and TypeConsts that are not in the Lattice are only subtypes of themselves.
+// It doesn't have to be a complete lattice: TypeConsts that have no least upper bound (or greatest lower bound) simply can't be joined (or met).
+type Lattice struct {
+	consts []TypeConst               // the TypeConsts in the order they were declared
+	supers map[TypeConst][]TypeConst // the declared supertypes of each TypeConst
+}
+
+// NewLattice creates a new empty Lattice
+func NewLattice() *Lattice {
+	return &Lattice{supers: make(map[TypeConst][]TypeConst)}
+}
+
+// Add declares sub as a subtype of super. The subtype relation may not be cyclic.
+func (l *Lattice) Add(sub, super TypeConst) error {
+	if l.IsSubtype(super, sub) {
+		return errors.Errorf("%v is already a subtype of %v. %v cannot be a subtype of %v as well", super, sub, sub, super)
+	}
+	for _, c := range []TypeConst{sub, super} {
+		if _, ok := l.supers[c]; !ok {
+			l.supers[c] = nil
+			l.consts = append(l.consts, c)
+		}
+	}
+	l.supers[sub] = append(l.supers[sub], super)
+	return nil
+}
+
+// IsSubtype returns true if a <: b
+func (l *Lattice) IsSubtype(a, b TypeConst) bool {
+	if a == b {
+		return true
+	}
+	for _, s := range l.supers[a] {
+		if l.IsSubtype(s, b) {
+			return true
+		}
+	}
+	return false
+}
+
+// Join returns the least upper bound of a and b: the least TypeConst that both of them are subtypes of.
+func (l *Lattice) Join(a, b TypeConst) (TypeConst, bool) {
+	var uppers []TypeConst
+	for _, c := range l.candidates(a, b) {
+		if l.IsSubtype(a, c) && l.IsSubtype(b, c) {
+			uppers = append(uppers, c)
+		}
+	}
+	return least(uppers, l.IsSubtype)
+}
+
+// Meet returns the greatest lower bound of a and b: the greatest TypeConst that is a subtype of both of them.
+func (l *Lattice) Meet(a, b TypeConst) (TypeConst, bool) {
+	var lowers []TypeConst
+	for _, c := range l.candidates(a, b) {
+		if l.IsSubtype(c, a) && l.IsSubtype(c, b) {
+			lowers = append(lowers, c)
+		}
+	}
+	return least(lowers, func(x, y TypeConst) bool { return l.IsSubtype(y, x) })
+}
+
+// candidates returns the TypeConsts that may be the join or the meet of a and b. a and b might not be in the Lattice.
+func (l *Lattice) candidates(a, b TypeConst) []TypeConst {
+	return append([]TypeConst{a, b}, l.consts...)
+}
+
+// least returns the TypeConst that is less than or equal to all the others
+func least(cs []TypeConst, le func(a, b TypeConst) bool) (TypeConst, bool) {
+	for _, c := range cs {
+		isLeast := true
+		for _, d := range cs {
+			if !le(c, d) {
+				isLeast = false
+				break
+			}
+		}
+		if isLeast {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// WithSubtyping makes the inference algorithm allow the argument of a function to be a subtype of its parameter, with the subtype relation of the TypeConsts in the Lattice.
+// (Check allows an expression to be of a subtype of the type it is checked against as well.)
+//
+// The subtype constraints are solved after the equality constraints. FunctionTypes are contravariant in their arguments and covariant in their results,
+// and the types made of any other type constructor are invariant. A TypeVariable that is a subtype (or a supertype) of a FunctionType is the same FunctionType.
+// What remains are bounds on TypeVariables, which are propagated through the Lattice and checked.
+//
+// When a type is generalized, its bounds are simplified: a TypeVariable that only occurs covariantly is replaced with its lower bound,
+// and one that only occurs contravariantly with its upper bound. The bounds that remain are the Predicates of the scheme (see SubtypeClass).
+func WithSubtyping(l *Lattice) InferOpt {
+	return func(infer *inferer) { infer.lattice = l }
+}
+
+// SubtypeConstraint is a constraint that says that sub has to be a subtype of super. It's the counterpart of Constraint when subtyping is allowed.
+type SubtypeConstraint struct {
+	sub, super Type
+	origin     *Origin
+}
+
+func (c SubtypeConstraint) Apply(sub Subs) Substitutable {
+	c.sub = cloneType(c.sub).Apply(sub).(Type)
+	c.super = cloneType(c.super).Apply(sub).(Type)
+	return c
+}
+
+func (c SubtypeConstraint) FreeTypeVar() TypeVarSet {
+	return c.sub.FreeTypeVar().Union(c.super.FreeTypeVar())
+}
+
+func (c SubtypeConstraint) Format(state fmt.State, r rune) {
+	fmt.Fprintf(state, "{%v <: %v}", c.sub, c.super)
+}
+
+// Origin returns the origin of the constraint.
+func (c SubtypeConstraint) Origin() *Origin { return c.origin }
+
+// fail creates the error for when the constraint cannot be satisfied
+func (c SubtypeConstraint) fail(sub, super Type) error {
+	return withPos(c.origin, SubtypeError{Sub: sub, Super: super, Origin: c.origin})
+}
+
+// solveSubtypes decomposes the subtype constraints with the solution of the equality constraints,
+// until they are all bounds on TypeVariables (or between TypeConsts). Decomposing them may lead to more equality constraints, which are then solved as well.
+// It returns the solution of the equality constraints, and adjusts the levels with the part of it that is new.
+func (infer *inferer) solveSubtypes(sub Subs) (Subs, error) {
+	for {
+		var atoms []SubtypeConstraint
+		var eqs Constraints
+		stack := make([]SubtypeConstraint, 0, len(infer.subtypes))
+		for i := len(infer.subtypes) - 1; i >= 0; i-- {
+			stack = append(stack, infer.subtypes[i].Apply(sub).(SubtypeConstraint))
+		}
+		for len(stack) > 0 {
+			c := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			a, b := expandAlias(c.sub), expandAlias(c.super)
+			if a.Eq(b) {
+				continue
+			}
+			af, aIsFn := a.(*FunctionType)
+			bf, bIsFn := b.(*FunctionType)
+			switch {
+			case aIsFn && bIsFn:
+				// functions are contravariant in their arguments, and covariant in their results. Their effects are invariant
+				stack = append(stack, SubtypeConstraint{af.b, bf.b, c.origin}, SubtypeConstraint{bf.a, af.a, c.origin})
+				if af.eff != nil || bf.eff != nil {
+					eqs = append(eqs, Constraint{effectRow(af.eff), effectRow(bf.eff), c.origin})
+				}
+			case isAtom(a) && isAtom(b):
+				atoms = append(atoms, SubtypeConstraint{a, b, c.origin})
+			default:
+				eqs = append(eqs, Constraint{a, b, c.origin})
+			}
+		}
+		infer.subtypes = atoms
+		if len(eqs) == 0 {
+			break
+		}
+
+		// the equality constraints are solved on top of sub, which they have been applied with already
+		infer.cs = append(infer.cs, eqs...)
+		next, err := infer.solve(eqs)
+		if err != nil {
+			return nil, err
+		}
+		infer.levels.Adjust(next)
+		sub = extend(sub, next)
+	}
+	return sub, infer.checkBounds()
+}
+
+// isAtom returns true if the type is a TypeVariable or a TypeConst: the types that subtype constraints are left on once they are decomposed.
+func isAtom(t Type) bool {
+	switch t.(type) {
+	case TypeVariable, TypeConst:
+		return true
+	}
+	return false
+}
+
+// checkBounds propagates the bounds of the TypeVariables in the atomic subtype constraints through the Lattice,
+// and checks that every TypeVariable's lower bound is a subtype of its upper bound.
+func (infer *inferer) checkBounds() error {
+	lower := make(map[TypeVariable]TypeConst)
+	upper := make(map[TypeVariable]TypeConst)
+
+	// raise raises the lower bound of tv to include t, and drop drops the upper bound of tv to below t. They return true if the bound changed.
+	raise := func(tv TypeVariable, t TypeConst, c SubtypeConstraint) (bool, error) {
+		cur, ok := lower[tv]
+		if !ok {
+			lower[tv] = t
+			return true, nil
+		}
+		j, ok := infer.lattice.Join(cur, t)
+		if !ok {
+			return false, c.fail(t, cur)
+		}
+		lower[tv] = j
+		return j != cur, nil
+	}
+	drop := func(tv TypeVariable, t TypeConst, c SubtypeConstraint) (bool, error) {
+		cur, ok := upper[tv]
+		if !ok {
+			upper[tv] = t
+			return true, nil
+		}
+		m, ok := infer.lattice.Meet(cur, t)
+		if !ok {
+			return false, c.fail(cur, t)
+		}
+		upper[tv] = m
+		return m != cur, nil
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, c := range infer.subtypes {
+			var ch bool
+			var err error
+			switch a := c.sub.(type) {
+			case TypeConst:
+				switch b := c.super.(type) {
+				case TypeConst:
+					if !infer.lattice.IsSubtype(a, b) {
+						return c.fail(a, b)
+					}
+				case TypeVariable:
+					ch, err = raise(b, a, c)
+				}
+			case TypeVariable:
+				switch b := c.super.(type) {
+				case TypeConst:
+					ch, err = drop(a, b, c)
+				case TypeVariable:
+					// lower bounds flow up, and upper bounds flow down
+					if l, ok := lower[a]; ok {
+						if ch, err = raise(b, l, c); err != nil {
+							return err
+						}
+					}
+					if u, ok := upper[b]; ok {
+						var ch2 bool
+						ch2, err = drop(a, u, c)
+						ch = ch || ch2
+					}
+				}
+			}
+			if err != nil {
+				return err
+			}
+			changed = changed || ch
+		}
+	}
+
+	for _, c := range infer.subtypes {
+		for _, tv := range []Type{c.sub, c.super} {
+			if v, ok := tv.(TypeVariable); ok {
+				l, hasLower := lower[v]
+				u, hasUpper := upper[v]
+				if hasLower && hasUpper && !infer.lattice.IsSubtype(l, u) {
+					return c.fail(l, u)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// simplifySubtypes simplifies the atomic subtype constraints on the TypeVariables that are generalized, and returns them as Predicates.
+// The subtype constraints that are not on any generalized TypeVariables, once they are simplified, stay in infer.subtypes.
+//
+// A generalized TypeVariable that doesn't occur in t is replaced with its lower bound if it has no other lower bounds (or with its upper bound
+// if it has no other upper bounds), or it is removed from between the TypeVariables it is bounded by.
+// One whose lower and upper bounds are the same TypeConst is replaced with it.
+// One that only occurs covariantly in t is replaced with its lower bound, if that is its only lower bound, and one that only occurs contravariantly is replaced with its upper bound.
+func (infer *inferer) simplifySubtypes(t Type, sub Subs, generalized func(TypeVariable) bool) (Type, Predicates, error) {
+	var local, deferred []SubtypeConstraint
+	for _, c := range infer.subtypes {
+		c = c.Apply(sub).(SubtypeConstraint)
+		if anyGeneralized(c, generalized) {
+			local = append(local, c)
+		} else {
+			deferred = append(deferred, c)
+		}
+	}
+	infer.subtypes = deferred
+	if len(local) == 0 {
+		return t, nil, nil
+	}
+
+	for simplified := true; simplified; {
+		simplified = false
+		pos, neg := make(map[TypeVariable]bool), make(map[TypeVariable]bool)
+		polarities(t, true, pos, neg)
+
+		for _, tv := range atomVars(local) {
+			if !generalized(tv) {
+				continue
+			}
+			lower, upper, lowerVars, upperVars, err := infer.bounds(tv, local)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			var replacement Type
+			switch {
+			case lower != "" && lower == upper:
+				// tv is bounded from both sides by the same TypeConst, so that is what it is
+				replacement = lower
+			case !pos[tv] && !neg[tv]:
+				switch {
+				case lower != "" && len(lowerVars) == 0:
+					replacement = lower
+				case upper != "" && len(upperVars) == 0:
+					replacement = upper
+				case lower == "" && upper == "":
+					// tv is only between other TypeVariables: u <: tv <: w becomes u <: w
+					var rest []SubtypeConstraint
+					for _, c := range local {
+						if c.sub != tv && c.super != tv {
+							rest = append(rest, c)
+						}
+					}
+					for _, u := range lowerVars {
+						for _, w := range upperVars {
+							rest = append(rest, SubtypeConstraint{u, w, nil})
+						}
+					}
+					local = rest
+					simplified = true
+				}
+			case pos[tv] && !neg[tv]:
+				switch {
+				case lower != "" && len(lowerVars) == 0:
+					replacement = lower
+				case lower == "" && len(lowerVars) == 1:
+					replacement = lowerVars[0]
+				}
+			case neg[tv] && !pos[tv]:
+				switch {
+				case upper != "" && len(upperVars) == 0:
+					replacement = upper
+				case upper == "" && len(upperVars) == 1:
+					replacement = upperVars[0]
+				}
+			}
+
+			if replacement != nil {
+				s := mSubs{tv: replacement}
+				t = cloneType(t).Apply(s).(Type)
+				for i, c := range local {
+					local[i] = c.Apply(s).(SubtypeConstraint)
+				}
+				simplified = true
+			}
+			if simplified {
+				break
+			}
+		}
+
+		// the constraints that hold trivially are removed, as are the duplicates
+		var rest []SubtypeConstraint
+	next:
+		for _, c := range local {
+			if c.sub.Eq(c.super) {
+				continue
+			}
+			if a, ok := c.sub.(TypeConst); ok {
+				if b, ok := c.super.(TypeConst); ok {
+					if !infer.lattice.IsSubtype(a, b) {
+						return nil, nil, c.fail(a, b)
+					}
+					continue
+				}
+			}
+			for _, r := range rest {
+				if r.sub.Eq(c.sub) && r.super.Eq(c.super) {
+					continue next
+				}
+			}
+			rest = append(rest, c)
+		}
+		local = rest
+	}
+
+	// the constraints that are no longer on any generalized TypeVariables are solved with the enclosing expression
+	var ps Predicates
+	for _, c := range local {
+		if !anyGeneralized(c, generalized) {
+			infer.subtypes = append(infer.subtypes, c)
+			continue
+		}
+		ps = append(ps, NewPredicate(SubtypeClass, c.sub, c.super))
+	}
+	return t, ps, nil
+}
+
+// anyGeneralized returns true if any of the TypeVariables of the subtype constraint is generalized
+func anyGeneralized(c SubtypeConstraint, generalized func(TypeVariable) bool) bool {
+	for _, tv := range c.FreeTypeVar() {
+		if generalized(tv) {
+			return true
+		}
+	}
+	return false
+}
+
+// bounds returns the bounds of the TypeVariable in the atomic subtype constraints: the join of its lower TypeConsts, the meet of its upper TypeConsts,
+// and the TypeVariables it's bounded by.
+func (infer *inferer) bounds(tv TypeVariable, cs []SubtypeConstraint) (lower, upper TypeConst, lowerVars, upperVars TypeVarSet, err error) {
+	for _, c := range cs {
+		switch {
+		case c.super == tv:
+			switch l := c.sub.(type) {
+			case TypeConst:
+				if lower == "" {
+					lower = l
+				} else if lower, err = join(infer.lattice, lower, l, c); err != nil {
+					return
+				}
+			case TypeVariable:
+				if !lowerVars.Contains(l) {
+					lowerVars = append(lowerVars, l)
+				}
+			}
+		case c.sub == tv:
+			switch u := c.super.(type) {
+			case TypeConst:
+				if upper == "" {
+					upper = u
+				} else if upper, err = meet(infer.lattice, upper, u, c); err != nil {
+					return
+				}
+			case TypeVariable:
+				if !upperVars.Contains(u) {
+					upperVars = append(upperVars, u)
+				}
+			}
+		}
+	}
+	return
+}
+
+func join(l *Lattice, a, b TypeConst, c SubtypeConstraint) (TypeConst, error) {
+	j, ok := l.Join(a, b)
+	if !ok {
+		return "", c.fail(b, a)
+	}
+	return j, nil
+}
+
+func meet(l *Lattice, a, b TypeConst, c SubtypeConstraint) (TypeConst, error) {
+	m, ok := l.Meet(a, b)
+	if !ok {
+		return "", c.fail(a, b)
+	}
+	return m, nil
+}
+
+// atomVars returns the TypeVariables of the atomic subtype constraints, in the order they appear.
+func atomVars(cs []SubtypeConstraint) TypeVarSet {
+	var tvs TypeVarSet
+	for _, c := range cs {
+		for _, t := range []Type{c.sub, c.super} {
+			if tv, ok := t.(TypeVariable); ok && !tvs.Contains(tv) {
+				tvs = append(tvs, tv)
+			}
+		}
+	}
+	return tvs
+}
+
+// polarities records whether the TypeVariables of t occur covariantly (pos) or contravariantly (neg).
+// The arguments of FunctionTypes are contravariant, and the component types of any other types are invariant: both covariant and contravariant.
+func polarities(t Type, positive bool, pos, neg map[TypeVariable]bool) {
+	switch tt := t.(type) {
+	case TypeVariable:
+		if positive {
+			pos[tt] = true
+		} else {
+			neg[tt] = true
+		}
+	case *FunctionType:
+		polarities(tt.a, !positive, pos, neg)
+		polarities(tt.b, positive, pos, neg)
+	default:
+		for _, c := range t.Types() {
+			polarities(c, true, pos, neg)
+			polarities(c, false, pos, neg)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package hm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func numLattice() *Lattice {
+	l := NewLattice()
+	l.Add("Int", "Float")
+	l.Add("Float", "Number")
+	l.Add("Rational", "Number")
+	return l
+}
+
+func TestLattice(t *testing.T) {
+	l := numLattice()
+	if err := l.Add("Number", "Int"); err == nil {
+		t.Errorf("Expected the subtype relation to not be cyclic")
+	}
+
+	subtypes := []struct {
+		a, b    TypeConst
+		correct bool
+	}{
+		{"Int", "Int", true},
+		{"Int", "Float", true},
+		{"Int", "Number", true},
+		{"Float", "Int", false},
+		{"Rational", "Float", false},
+		{"Bool", "Bool", true},
+		{"Bool", "Number", false},
+	}
+	for _, st := range subtypes {
+		if got := l.IsSubtype(st.a, st.b); got != st.correct {
+			t.Errorf("Expected %v <: %v to be %t", st.a, st.b, st.correct)
+		}
+	}
+
+	joins := []struct {
+		a, b       TypeConst
+		join, meet TypeConst
+		ok         bool
+	}{
+		{"Int", "Float", "Float", "Int", true},
+		{"Int", "Rational", "Number", "", true},
+		{"Float", "Float", "Float", "Float", true},
+		{"Int", "Bool", "", "", false},
+	}
+	for _, j := range joins {
+		join, ok := l.Join(j.a, j.b)
+		if ok != j.ok || join != j.join {
+			t.Errorf("Expected the join of %v and %v to be %q. Got %q", j.a, j.b, j.join, join)
+		}
+		meet, ok := l.Meet(j.a, j.b)
+		if (j.meet != "") != ok || meet != j.meet {
+			t.Errorf("Expected the meet of %v and %v to be %q. Got %q", j.a, j.b, j.meet, meet)
+		}
+	}
+}
+
+func TestInfer_Subtypes(t *testing.T) {
+	a := TypeVariable('a')
+	Int, Float, Bool := TypeConst("Int"), TypeConst("Float"), TypeConst("Bool")
+	env := SimpleEnv{
+		"mul":   NewScheme(TypeVarSet{a}, NewFnType(a, a, a)),
+		"two":   NewScheme(nil, Int),
+		"x":     NewScheme(nil, Float),
+		"ok":    NewScheme(nil, Bool),
+		"round": NewScheme(nil, NewFnType(Float, Int)),
+		"apply": NewScheme(TypeVarSet{a}, NewFnType(NewFnType(Int, Float), a, Float)),
+	}
+
+	subtypeTests := []struct {
+		name string
+		expr Expression
+
+		correct string
+		err     bool
+	}{
+		{"mul two x", app{app{lit("mul"), lit("two")}, lit("x")}, "∀[]: Float", false},
+		{"mul x two", app{app{lit("mul"), lit("x")}, lit("two")}, "∀[]: Float", false},
+		{"mul two two", app{app{lit("mul"), lit("two")}, lit("two")}, "∀[]: Int", false},
+		{"λy. mul two y", λ{"y", app{app{lit("mul"), lit("two")}, lit("y")}}, "∀[a]: Int <: a ⇒ a → a", false},
+		{"let f = λy. mul two y in f x", let{"f", λ{"y", app{app{lit("mul"), lit("two")}, lit("y")}}, app{lit("f"), lit("x")}}, "∀[]: Float", false},
+		{"round two", app{lit("round"), lit("two")}, "∀[]: Int", false},
+		{"apply round", app{lit("apply"), lit("round")}, "∀[a]: a → Float", false},
+		{"(λy. let f = round (mul y two) in (f, y)) x", app{λ{"y", let{"f", app{lit("round"), app{app{lit("mul"), lit("y")}, lit("two")}}, tuple{lit("f"), lit("y")}}}, lit("x")}, "∀[]: (Int, Float)", false},
+
+		{"mul two ok", app{app{lit("mul"), lit("two")}, lit("ok")}, "", true},
+		{"round ok", app{lit("round"), lit("ok")}, "", true},
+		{"let f = λy. mul two y in f ok", let{"f", λ{"y", app{app{lit("mul"), lit("two")}, lit("y")}}, app{lit("f"), lit("ok")}}, "", true},
+		{"(λy. let f = round (mul y two) in y) ok", app{λ{"y", let{"f", app{lit("round"), app{app{lit("mul"), lit("y")}, lit("two")}}, lit("y")}}, lit("ok")}, "", true},
+	}
+
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		opts = append(opts, WithSubtyping(numLattice()))
+		for _, st := range subtypeTests {
+			sch, err := Infer(env, st.expr, opts...)
+			if st.err {
+				var te TypeError
+				if !errors.As(err, &te) || te.Code() != CodeSubtype {
+					t.Errorf("Test %q: Expected a subtype error. Got %v, %v", st.name, sch, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("Test %q: %v", st.name, err)
+				continue
+			}
+			if got := fmt.Sprintf("%v", sch); got != st.correct {
+				t.Errorf("Test %q: Expected %v. Got %v", st.name, st.correct, got)
+			}
+		}
+	}
+
+	// without subtyping, Int and Float are different types
+	var te TypeError
+	if sch, err := Infer(env, app{app{lit("mul"), lit("two")}, lit("x")}); !errors.As(err, &te) || te.Code() != CodeUnification {
+		t.Errorf("Expected a unification error without WithSubtyping. Got %v, %v", sch, err)
+	}
+}
+
+func TestCheck_Subtypes(t *testing.T) {
+	Int, Float, Bool := TypeConst("Int"), TypeConst("Float"), TypeConst("Bool")
+	env := SimpleEnv{"two": NewScheme(nil, Int)}
+
+	if err := Check(env, lit("two"), Float, WithSubtyping(numLattice())); err != nil {
+		t.Errorf("Expected an Int to be checked against Float. Got %v", err)
+	}
+	var te TypeError
+	if err := Check(env, lit("two"), Bool, WithSubtyping(numLattice())); !errors.As(err, &te) || te.Code() != CodeSubtype {
+		t.Errorf("Expected a subtype error. Got %v", err)
+	}
+	if err := Check(env, lit("two"), Float); !errors.As(err, &te) || te.Code() != CodeUnification {
+		t.Errorf("Expected a unification error without WithSubtyping. Got %v", err)
+	}
+}
@@ -88,3 +88,189 @@ type variable string
 func (t variable) Body() Expression { return nil }
 func (t variable) Name() string     { return string(t) }
 func (t variable) Type() Type       { return nil }
+
+// unhandled is an Expression that doesn't implement any of the other Expression interfaces
+type unhandled struct{}
+
+func (t unhandled) Body() Expression { return nil }
+
+// span implements Positioner for testing.
+type span struct {
+	start, end Position
+}
+
+func (s span) Pos() (start, end Position) { return s.start, s.end }
+
+func newSpan(line, col, length int) span {
+	return span{
+		start: Position{Filename: "test.hm", Line: line, Column: col},
+		end:   Position{Filename: "test.hm", Line: line, Column: col + length},
+	}
+}
+
+// positioned variants of the test expressions
+
+type posLit struct {
+	lit
+	span
+}
+
+type posApp struct {
+	app
+	span
+}
+
+type posλ struct {
+	λ
+	span
+}
+
+// record expressions for testing
+
+type sel struct {
+	rec   Expression
+	label string
+}
+
+func (n sel) Body() Expression { return n.rec }
+func (n sel) Label() string    { return n.label }
+func (n sel) IsSelect() bool   { return true }
+
+type ext struct {
+	rec   Expression
+	label string
+	val   Expression
+}
+
+func (n ext) Body() Expression  { return n.rec }
+func (n ext) Label() string     { return n.label }
+func (n ext) Value() Expression { return n.val }
+
+type restr struct {
+	rec   Expression
+	label string
+}
+
+func (n restr) Body() Expression { return n.rec }
+func (n restr) Label() string    { return n.label }
+func (n restr) IsRestrict() bool { return true }
+
+// emptyRec is the empty record {}
+type emptyRec struct{}
+
+func (n emptyRec) Body() Expression { return nil }
+func (n emptyRec) Type() Type       { return NewRow(nil) }
+
+// case expressions and patterns for testing
+
+type caseExpr struct {
+	scrutinee Expression
+	alts      []Alternative
+}
+
+func (n caseExpr) Body() Expression            { return n.scrutinee }
+func (n caseExpr) Alternatives() []Alternative { return n.alts }
+
+type alt struct {
+	pat  Pattern
+	body Expression
+}
+
+func (n alt) Body() Expression { return n.body }
+func (n alt) Pattern() Pattern { return n.pat }
+
+type wildP struct{}
+
+func (p wildP) IsWildcard() bool { return true }
+
+type varP string
+
+func (p varP) Name() string       { return string(p) }
+func (p varP) IsVarPattern() bool { return true }
+
+type litP string
+
+func (p litP) Name() string { return string(p) }
+func (p litP) Type() Type   { return lit(p).Type() }
+func (p litP) IsLit() bool  { return true }
+
+type conP struct {
+	name string
+	args []Pattern
+}
+
+func (p conP) Name() string    { return p.name }
+func (p conP) Args() []Pattern { return p.args }
+
+type tupleP []Pattern
+
+func (p tupleP) Elems() []Pattern { return p }
+
+type recordP struct {
+	labels []string
+	elems  []Pattern
+}
+
+func (p recordP) Elems() []Pattern { return p.elems }
+func (p recordP) Labels() []string { return p.labels }
+
+// tuple expressions for testing
+
+type tuple []Expression
+
+func (n tuple) Body() Expression    { return nil }
+func (n tuple) Elems() []Expression { return n }
+
+type proj struct {
+	rec   Expression
+	index int
+	label string
+}
+
+func (n proj) Body() Expression { return n.rec }
+func (n proj) Index() int       { return n.index }
+func (n proj) Label() string    { return n.label }
+
+type ifExpr struct {
+	cond, then, els Expression
+}
+
+func (n ifExpr) Body() Expression { return n.cond }
+func (n ifExpr) Cond() Expression { return n.cond }
+func (n ifExpr) Then() Expression { return n.then }
+func (n ifExpr) Else() Expression { return n.els }
+
+type letrecGroup struct {
+	bindings []Binding
+	in       Expression
+}
+
+func (n letrecGroup) Body() Expression    { return n.in }
+func (n letrecGroup) Bindings() []Binding { return n.bindings }
+
+type binding struct {
+	name string
+	def  Expression
+}
+
+func (n binding) Name() string     { return n.name }
+func (n binding) Body() Expression { return n.def }
+
+type annotated struct {
+	expr Expression
+	sc   *Scheme
+}
+
+func (n annotated) Body() Expression    { return n.expr }
+func (n annotated) Annotation() *Scheme { return n.sc }
+
+type typedλ struct {
+	name string
+	t    Type
+	body Expression
+}
+
+func (n typedλ) Name() string     { return n.name }
+func (n typedλ) Body() Expression { return n.body }
+func (n typedλ) IsLambda() bool   { return true }
+func (n typedλ) ParamType() Type  { return n.t }
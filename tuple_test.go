@@ -0,0 +1,77 @@
+package hm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestInfer_Tuple(t *testing.T) {
+	a := TypeVariable('a')
+	env := SimpleEnv{
+		"id":     NewScheme(TypeVarSet{'a'}, NewFnType(a, a)),
+		"origin": NewScheme(nil, NewRecordTypeFields("Point", []Field{{"x", Float}, {"y", Bool}})),
+	}
+
+	tupleTests := []struct {
+		name string
+		expr Expression
+
+		correct string
+		err     ErrorCode
+	}{
+		{"(1, true)", tuple{lit("1"), lit("true")}, "∀[]: (Float, Bool)", 0},
+		{"()", tuple{}, "∀[]: ()", 0},
+		{"λx. (x, x)", λ{"x", tuple{lit("x"), lit("x")}}, "∀[a]: a → (a, a)", 0},
+		{"(1, true).1", proj{tuple{lit("1"), lit("true")}, 1, ""}, "∀[]: Bool", 0},
+		{"(id (1, true)).0", proj{app{lit("id"), tuple{lit("1"), lit("true")}}, 0, ""}, "∀[]: Float", 0},
+		{"let t = λx. (x, 1) in (t true).0", let{"t", λ{"x", tuple{lit("x"), lit("1")}}, proj{app{lit("t"), lit("true")}, 0, ""}}, "∀[]: Bool", 0},
+		{"origin.y", proj{lit("origin"), 0, "y"}, "∀[]: Bool", 0},
+
+		{"(1, true).2", proj{tuple{lit("1"), lit("true")}, 2, ""}, "", CodeProjection},
+		{"(1, true).-1", proj{tuple{lit("1"), lit("true")}, -1, ""}, "", CodeProjection},
+		{"origin.z", proj{lit("origin"), 0, "z"}, "", CodeProjection},
+		{"λx. x.0", λ{"x", proj{lit("x"), 0, ""}}, "", CodeProjection},
+		{"1.0", proj{lit("1"), 0, ""}, "", CodeProjection},
+		{"(let z = 1 in z, z)", tuple{let{"z", lit("1"), lit("z")}, lit("z")}, "", CodeUndefinedName},
+		{"(letrec z = 1 in z, z)", tuple{letrec{"z", lit("1"), lit("z")}, lit("z")}, "", CodeUndefinedName},
+	}
+
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		for _, tts := range tupleTests {
+			sch, err := Infer(env, tts.expr, opts...)
+			if tts.err != 0 {
+				var te TypeError
+				if !errors.As(err, &te) || te.Code() != tts.err {
+					t.Errorf("Test %q: Expected a %v error. Got %v, %v", tts.name, tts.err, sch, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("Test %q: %+v", tts.name, err)
+				continue
+			}
+			if got := fmt.Sprintf("%v", sch); got != tts.correct {
+				t.Errorf("Test %q: Expected %v. Got %v", tts.name, tts.correct, got)
+			}
+		}
+	}
+}
+
+func TestProjectionError(t *testing.T) {
+	pair := NewRecordType("", proton, neutron)
+	errs := []struct {
+		err     ProjectionError
+		correct string
+	}{
+		{ProjectionError{Type: pair, Index: 2}, "Cannot project element 2 of (proton, neutron): it has 2 elements"},
+		{ProjectionError{Type: pair, Label: "x"}, `Cannot project field "x" of (proton, neutron): it has no such field`},
+		{ProjectionError{Type: TypeVariable('a')}, "Cannot project from a: it is not known to be a record"},
+	}
+	for _, e := range errs {
+		if e.err.Error() != e.correct {
+			t.Errorf("Expected %q. Got %q", e.correct, e.err.Error())
+		}
+	}
+}
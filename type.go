@@ -2,6 +2,7 @@ package hm
 
 import (
 	"fmt"
+	"reflect"
 )
 
 // Type represents all the possible type constructors.
@@ -22,6 +23,29 @@ type Substitutable interface {
 	FreeTypeVar() TypeVarSet
 }
 
+// cloneType clones the type if it is a Cloner. Types that aren't Cloners are assumed to be immutable.
+func cloneType(t Type) Type {
+	if c, ok := t.(Cloner); ok {
+		return c.Clone().(Type)
+	}
+	return t
+}
+
+// sameConstructor returns true if both types are built by the same type constructor, regardless of their component types.
+func sameConstructor(a, b Type) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	switch at := a.(type) {
+	case *Record:
+		return at.compatible(b.(*Record))
+	case *ConstructedType:
+		bt := b.(*ConstructedType)
+		return at.name == bt.name && len(at.args) == len(bt.args)
+	}
+	return true
+}
+
 // TypeConst are the default implementation of a constant type. Feel free to implement your own. TypeConsts should be immutable (so no pointer types plz)
 type TypeConst string
 
@@ -32,14 +56,25 @@ func (t TypeConst) Normalize(k, v TypeVarSet) (Type, error) { return t, nil }
 func (t TypeConst) Types() Types                            { return nil }
 func (t TypeConst) String() string                          { return string(t) }
 func (t TypeConst) Format(s fmt.State, c rune)              { fmt.Fprintf(s, "%s", string(t)) }
-func (t TypeConst) Eq(other Type) bool                      { return other == t }
+func (t TypeConst) Eq(other Type) bool                      { return expandAlias(other) == t }
 
-// Record is a basic record/tuple type. It takes an optional name.
+// Record is a basic record/tuple type. It takes an optional name, and its fields may be labelled.
+//
+// Records are structural by default: records with different names unify as long as their fields do.
+// A nominal record only unifies with nominal records of the same name.
 type Record struct {
-	ts   []Type
-	name string
+	ts      []Type
+	labels  []string // nil if the fields are not labelled
+	name    string
+	nominal bool
 }
 
+// RecordOpt is a functional option for NewRecordTypeFields
+type RecordOpt func(*Record)
+
+// Nominal makes the record nominal: it only unifies with nominal records of the same name.
+func Nominal() RecordOpt { return func(t *Record) { t.nominal = true } }
+
 // NewRecordType creates a new Record Type
 func NewRecordType(name string, ts ...Type) *Record {
 	return &Record{
@@ -48,12 +83,75 @@ func NewRecordType(name string, ts ...Type) *Record {
 	}
 }
 
+// NewRecordTypeFields creates a new Record Type with labelled fields
+func NewRecordTypeFields(name string, fields []Field, opts ...RecordOpt) *Record {
+	ts := make([]Type, len(fields))
+	labels := make([]string, len(fields))
+	for i, f := range fields {
+		ts[i] = f.Type
+		labels[i] = f.Label
+	}
+	retVal := &Record{
+		ts:     ts,
+		labels: labels,
+		name:   name,
+	}
+	for _, opt := range opts {
+		opt(retVal)
+	}
+	return retVal
+}
+
+// IsNominal returns true if the record only unifies with nominal records of the same name
+func (t *Record) IsNominal() bool { return t.nominal }
+
+// Labels returns the labels of the fields. It returns nil if the fields are not labelled.
+func (t *Record) Labels() []string { return t.labels }
+
+// Field returns the type of the field with the given label.
+func (t *Record) Field(label string) (Type, bool) {
+	for i, l := range t.labels {
+		if l == label {
+			return t.ts[i], true
+		}
+	}
+	return nil, false
+}
+
+// compatible returns false if the records can never be unified, regardless of the types of their fields
+func (t *Record) compatible(other *Record) bool {
+	if t.nominal || other.nominal {
+		if !(t.nominal && other.nominal && t.name == other.name) {
+			return false
+		}
+	}
+	if len(t.labels) != len(other.labels) {
+		return false
+	}
+	for i, l := range t.labels {
+		if l != other.labels[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// withTypes creates a record like t, but with the given types
+func (t *Record) withTypes(ts []Type) *Record {
+	return &Record{
+		ts:      ts,
+		labels:  t.labels,
+		name:    t.name,
+		nominal: t.nominal,
+	}
+}
+
 func (t *Record) Apply(subs Subs) Substitutable {
 	ts := make([]Type, len(t.ts))
 	for i, v := range t.ts {
 		ts[i] = v.Apply(subs).(Type)
 	}
-	return NewRecordType(t.name, ts...)
+	return t.withTypes(ts)
 }
 
 func (t *Record) FreeTypeVar() TypeVarSet {
@@ -79,7 +177,7 @@ func (t *Record) Normalize(k, v TypeVarSet) (Type, error) {
 			return nil, err
 		}
 	}
-	return NewRecordType(t.name, ts...), nil
+	return t.withTypes(ts), nil
 }
 
 func (t *Record) Types() Types {
@@ -89,8 +187,8 @@ func (t *Record) Types() Types {
 }
 
 func (t *Record) Eq(other Type) bool {
-	if ot, ok := other.(*Record); ok {
-		if len(ot.ts) != len(t.ts) {
+	if ot, ok := expandAlias(other).(*Record); ok {
+		if len(ot.ts) != len(t.ts) || ot.name != t.name || ot.nominal != t.nominal || !t.compatible(ot) {
 			return false
 		}
 		for i, v := range t.ts {
@@ -104,22 +202,24 @@ func (t *Record) Eq(other Type) bool {
 }
 
 func (t *Record) Format(f fmt.State, c rune) {
+	f.Write([]byte(t.name))
 	f.Write([]byte("("))
 	for i, v := range t.ts {
-		if i < len(t.ts)-1 {
-			fmt.Fprintf(f, "%v, ", v)
-		} else {
-			fmt.Fprintf(f, "%v)", v)
+		if i > 0 {
+			f.Write([]byte(", "))
 		}
+		if t.labels != nil {
+			fmt.Fprintf(f, "%s: ", t.labels[i])
+		}
+		fmt.Fprintf(f, "%v", v)
 	}
-
+	f.Write([]byte(")"))
 }
 
 func (t *Record) String() string { return fmt.Sprintf("%v", t) }
 
 // Clone implements Cloner
 func (t *Record) Clone() interface{} {
-	retVal := new(Record)
 	ts := BorrowTypes(len(t.ts))
 	for i, tt := range t.ts {
 		if c, ok := tt.(Cloner); ok {
@@ -128,8 +228,5 @@ func (t *Record) Clone() interface{} {
 			ts[i] = tt
 		}
 	}
-	retVal.ts = ts
-	retVal.name = t.name
-
-	return retVal
+	return t.withTypes(ts)
 }
@@ -2,14 +2,65 @@ package hm
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/pkg/errors"
 )
 
 // TypeVariable is a variable that ranges over the types - that is to say it can take any type.
+//
+// Any rune is a valid TypeVariable (TypeVariable('a'), TypeVariable('α') and so on). Beyond that,
+// NewTypeVar provides an unbounded supply of TypeVariables that will never clash with a rune.
 type TypeVariable rune
 
-func (t TypeVariable) Name() string { return string(t) }
+// tvBase is the first TypeVariable that is outside of the range of valid unicode code points.
+// TypeVariables from tvBase onwards are generated by NewTypeVar.
+const tvBase TypeVariable = 0x110000
+
+// NewTypeVar returns the nth TypeVariable. The first 26 TypeVariables are the letters 'a' to 'z'.
+// After that, the TypeVariables are printed as a1, b1 ... z1, a2, b2 and so on.
+//
+// NewTypeVar preserves ordering: if i < j then NewTypeVar(i) < NewTypeVar(j).
+func NewTypeVar(n int) TypeVariable {
+	if n < 0 {
+		panic("Expected a non-negative integer")
+	}
+	if n < len(letters) {
+		return TypeVariable(letters[n])
+	}
+	return tvBase + TypeVariable(n)
+}
+
+// unusedTypeVars returns the first n TypeVariables from NewTypeVar that are not in used.
+// It names the TypeVariables that are bound by ForallTypes and RecursiveTypes, so that they don't clash with the TypeVariables around them.
+func unusedTypeVars(used TypeVarSet, n int) TypeVarSet {
+	retVal := make(TypeVarSet, 0, n)
+	for i := 0; len(retVal) < n; i++ {
+		if tv := NewTypeVar(i); !used.Contains(tv) {
+			retVal = append(retVal, tv)
+		}
+	}
+	return retVal
+}
+
+// boundUsed returns the TypeVariables that a TypeVariable bound in the body can't be renamed to when the substitution is applied:
+// the TypeVariables of the body and of the substitution, and the other bound TypeVariables.
+func boundUsed(bound TypeVarSet, body Type, sub Subs) TypeVarSet {
+	used := append(append(TypeVarSet(nil), bound...), body.FreeTypeVar()...)
+	for _, s := range sub.Iter() {
+		used = append(append(used, s.Tv), s.T.FreeTypeVar()...)
+	}
+	return used
+}
+
+func (t TypeVariable) Name() string {
+	if t >= tvBase {
+		n := int(t - tvBase)
+		return string(letters[n%len(letters)]) + strconv.Itoa(n/len(letters))
+	}
+	return string(t)
+}
+
 func (t TypeVariable) Apply(sub Subs) Substitutable {
 	if sub == nil {
 		return t
@@ -31,6 +82,6 @@ func (t TypeVariable) Normalize(k, v TypeVarSet) (Type, error) {
 }
 
 func (t TypeVariable) Types() Types               { return nil }
-func (t TypeVariable) String() string             { return string(t) }
-func (t TypeVariable) Format(s fmt.State, c rune) { fmt.Fprintf(s, "%c", rune(t)) }
-func (t TypeVariable) Eq(other Type) bool         { return other == t }
+func (t TypeVariable) String() string             { return t.Name() }
+func (t TypeVariable) Format(s fmt.State, c rune) { fmt.Fprintf(s, "%s", t.Name()) }
+func (t TypeVariable) Eq(other Type) bool         { return expandAlias(other) == t }
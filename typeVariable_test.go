@@ -85,3 +85,51 @@ func TestTypeConst(t *testing.T) {
 		t.Error("Const types should return itself")
 	}
 }
+
+func TestNewTypeVar(t *testing.T) {
+	for i := 0; i < len(letters); i++ {
+		if tv := NewTypeVar(i); tv != TypeVariable(letters[i]) {
+			t.Errorf("Expected NewTypeVar(%d) to be %c. Got %v instead", i, letters[i], tv)
+		}
+	}
+
+	names := map[int]string{26: "a1", 27: "b1", 51: "z1", 52: "a2", 1000: "m38"}
+	for i, name := range names {
+		tv := NewTypeVar(i)
+		if tv.Name() != name {
+			t.Errorf("Expected NewTypeVar(%d) to be named %q. Got %q instead", i, name, tv.Name())
+		}
+		if s := fmt.Sprintf("%v", tv); s != name {
+			t.Errorf("Expected NewTypeVar(%d) to be printed as %q. Got %q instead", i, name, s)
+		}
+	}
+
+	// ordering and uniqueness
+	seen := make(map[TypeVariable]struct{})
+	for i := 0; i < 1000; i++ {
+		tv := NewTypeVar(i)
+		if _, ok := seen[tv]; ok {
+			t.Errorf("NewTypeVar(%d) = %v has been generated before", i, tv)
+		}
+		seen[tv] = struct{}{}
+
+		if i > 0 && NewTypeVar(i-1) >= tv {
+			t.Errorf("Expected NewTypeVar(%d) < NewTypeVar(%d)", i-1, i)
+		}
+	}
+}
+
+func TestUnusedTypeVars(t *testing.T) {
+	if got := unusedTypeVars(TypeVarSet{'a', 'c'}, 2); !got.Equals(TypeVarSet{'b', 'd'}) {
+		t.Errorf("Expected [b d]. Got %v", got)
+	}
+
+	// after z comes a1, not the rune after z
+	var letterSet TypeVarSet
+	for _, l := range letters {
+		letterSet = append(letterSet, TypeVariable(l))
+	}
+	if got := unusedTypeVars(letterSet, 1); got[0] != NewTypeVar(len(letters)) {
+		t.Errorf("Expected %v. Got %v", NewTypeVar(len(letters)), got)
+	}
+}
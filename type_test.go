@@ -0,0 +1,101 @@
+package hm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestRecord(t *testing.T) {
+	point := NewRecordTypeFields("Point", []Field{{"x", TypeVariable('a')}, {"y", proton}})
+	if fmt.Sprintf("%v", point) != "Point(x: a, y: proton)" {
+		t.Errorf("Wrong format. Got %v", point)
+	}
+	if fmt.Sprintf("%v", NewRecordType("", proton, neutron)) != "(proton, neutron)" {
+		t.Errorf("Wrong format. Got %v", NewRecordType("", proton, neutron))
+	}
+	if fmt.Sprintf("%v", NewRecordType("Unit")) != "Unit()" {
+		t.Errorf("Wrong format of the empty record. Got %v", NewRecordType("Unit"))
+	}
+
+	if ft, ok := point.Field("y"); !ok || ft != proton {
+		t.Errorf("Expected field y to be proton. Got %v", ft)
+	}
+	if _, ok := point.Field("z"); ok {
+		t.Error("Did not expect a field z")
+	}
+	if _, ok := NewRecordType("", proton).Field(""); ok {
+		t.Error("Positional records don't have labelled fields")
+	}
+
+	p2 := point.Apply(mSubs{'a': neutron}).(*Record)
+	if fmt.Sprintf("%v", p2) != "Point(x: neutron, y: proton)" {
+		t.Errorf("Apply should keep the name and labels. Got %v", p2)
+	}
+	p3, err := point.Normalize(TypeVarSet{'a'}, TypeVarSet{'b'})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%v", p3) != "Point(x: b, y: proton)" {
+		t.Errorf("Normalize should keep the name and labels. Got %v", p3)
+	}
+	if !point.Clone().(*Record).Eq(point) {
+		t.Error("Expected the clone to be equal")
+	}
+
+	// equality takes the name, labels and nominality into account
+	size := NewRecordTypeFields("Size", []Field{{"x", TypeVariable('a')}, {"y", proton}})
+	if point.Eq(size) {
+		t.Errorf("%v should not be equal to %v", point, size)
+	}
+	if point.Eq(NewRecordType("Point", TypeVariable('a'), proton)) {
+		t.Error("Labelled records are not equal to positional records")
+	}
+	if point.Eq(NewRecordTypeFields("Point", []Field{{"x", TypeVariable('a')}, {"y", proton}}, Nominal())) {
+		t.Error("Nominal records are not equal to structural records")
+	}
+}
+
+var unifyRecordTests = []struct {
+	name string
+	a, b Type
+	err  ErrorCode
+}{
+	{"structural records with different names",
+		NewRecordType("Point", TypeVariable('a'), proton), NewRecordType("Size", neutron, proton), 0},
+	{"labelled records",
+		NewRecordTypeFields("", []Field{{"x", TypeVariable('a')}}), NewRecordTypeFields("", []Field{{"x", proton}}), 0},
+	{"labelled records with different labels",
+		NewRecordTypeFields("", []Field{{"x", TypeVariable('a')}}), NewRecordTypeFields("", []Field{{"y", proton}}), CodeUnification},
+	{"labelled and positional records",
+		NewRecordTypeFields("", []Field{{"x", TypeVariable('a')}}), NewRecordType("", proton), CodeUnification},
+	{"nominal records",
+		NewRecordTypeFields("Point", []Field{{"x", TypeVariable('a')}}, Nominal()), NewRecordTypeFields("Point", []Field{{"x", proton}}, Nominal()), 0},
+	{"nominal records with different names",
+		NewRecordTypeFields("Point", []Field{{"x", TypeVariable('a')}}, Nominal()), NewRecordTypeFields("Size", []Field{{"x", proton}}, Nominal()), CodeUnification},
+	{"nominal and structural records",
+		NewRecordTypeFields("Point", []Field{{"x", TypeVariable('a')}}, Nominal()), NewRecordTypeFields("Point", []Field{{"x", proton}}), CodeUnification},
+}
+
+func TestUnify_Records(t *testing.T) {
+	for _, uts := range unifyRecordTests {
+		_, err := Unify(uts.a, uts.b)
+
+		s := newUFSolver()
+		s.solve(Constraints{{a: uts.a, b: uts.b}})
+
+		for engine, err := range map[string]error{"Unify": err, "UnionFind": s.err} {
+			if uts.err == 0 {
+				if err != nil {
+					t.Errorf("%s %q: %v", engine, uts.name, err)
+				}
+				continue
+			}
+			var te TypeError
+			if !errors.As(err, &te) || te.Code() != uts.err {
+				t.Errorf("%s %q: Expected a %v error. Got %v", engine, uts.name, uts.err, err)
+			}
+		}
+	}
+}
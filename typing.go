@@ -0,0 +1,138 @@
+package hm
+
+import "reflect"
+
+// Typing is the result of InferTyped: the type of the whole expression, as well as the type of every sub-expression.
+// The types are fully substituted with the solution of the constraints. Their TypeVariables are the ones generated during inference,
+// so the types of different nodes can be compared with one another. They are not normalized like the Scheme is.
+type Typing struct {
+	Scheme         *Scheme          // the type of the whole expression, as returned by Infer
+	Nodes          []TypedNode      // the visited Expressions, children before parents
+	Bindings       []Generalization // the schemes of the let-bound names, in the order that they are bound
+	Instantiations []Instantiation  // the instantiation of the scheme at every occurrence of a name
+}
+
+// TypedNode is an Expression with its type.
+type TypedNode struct {
+	Expr Expression
+	Type Type
+}
+
+// Generalization is the scheme that a let (or a letrec, or a binding of a LetRecGroup) binds its name to.
+type Generalization struct {
+	Expr   Expression
+	Name   string
+	Scheme *Scheme
+}
+
+// Instantiation records how the scheme of a name is instantiated where the name occurs.
+// Types are the types that the quantified TypeVariables of the Scheme are instantiated with, in order.
+type Instantiation struct {
+	Expr   Expression
+	Name   string
+	Scheme *Scheme
+	Types  Types
+}
+
+// InferTyped infers the type of the expression like Infer does, and records the type of every Expression that it visits.
+//
+// The Expressions are compared with ==, so TypeOf only finds the nodes of ASTs whose nodes are comparable (pointers, typically).
+// All the nodes are in Nodes regardless.
+func InferTyped(env Env, expr Expression, opts ...InferOpt) (*Typing, error) {
+	typing := new(Typing)
+	opts = append(opts[:len(opts):len(opts)], func(infer *inferer) { infer.typing = typing })
+	sch, err := Infer(env, expr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	typing.Scheme = sch
+	return typing, nil
+}
+
+// TypeOf returns the type of the expression. If the expression was visited more than once, the first of its types is returned.
+func (t *Typing) TypeOf(expr Expression) (Type, bool) {
+	for _, n := range t.Nodes {
+		if sameExpr(n.Expr, expr) {
+			return n.Type, true
+		}
+	}
+	return nil, false
+}
+
+// SchemeOf returns the scheme that the let-bound expression binds its name to.
+func (t *Typing) SchemeOf(expr Expression) (*Scheme, bool) {
+	for _, b := range t.Bindings {
+		if sameExpr(b.Expr, expr) {
+			return b.Scheme, true
+		}
+	}
+	return nil, false
+}
+
+// sameExpr compares the Expressions with ==. Expressions that are not comparable (such as structs with slices in them) are never the same.
+func sameExpr(a, b Expression) (same bool) {
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb || ta == nil || !ta.Comparable() {
+		return false
+	}
+	// a struct is comparable, but comparing it panics if it has an interface field that holds an uncomparable value
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
+
+// apply applies the solution of the constraints to the recorded types.
+func (t *Typing) apply(sub Subs) {
+	for i, n := range t.Nodes {
+		t.Nodes[i].Type = cloneType(n.Type).Apply(sub).(Type)
+	}
+	for i, b := range t.Bindings {
+		t.Bindings[i].Scheme = applyScheme(b.Scheme, sub)
+	}
+	for i, inst := range t.Instantiations {
+		ts := make(Types, len(inst.Types))
+		for j, it := range inst.Types {
+			ts[j] = cloneType(it).Apply(sub).(Type)
+		}
+		t.Instantiations[i].Types = ts
+		t.Instantiations[i].Scheme = applyScheme(inst.Scheme, sub)
+	}
+}
+
+// applyScheme applies the substitution to a copy of the scheme, so the scheme in the Env is left as it is.
+func applyScheme(s *Scheme, sub Subs) *Scheme {
+	c := s.Clone()
+	c.t = cloneType(c.t)
+	return c.Apply(sub).(*Scheme)
+}
+
+// typed records the type of a visited Expression
+func (infer *inferer) typed(expr Expression, t Type) {
+	if infer.typing == nil || t == nil {
+		return
+	}
+	infer.typing.Nodes = append(infer.typing.Nodes, TypedNode{Expr: expr, Type: t})
+}
+
+// generalized records the scheme of a let-bound name
+func (infer *inferer) generalized(expr Expression, name string, s *Scheme) {
+	if infer.typing == nil {
+		return
+	}
+	infer.typing.Bindings = append(infer.typing.Bindings, Generalization{Expr: expr, Name: name, Scheme: s})
+}
+
+// instanceRecorder is a Fresher that records the TypeVariables that a Scheme is instantiated with.
+type instanceRecorder struct {
+	Fresher
+	tvs Types
+}
+
+func (r *instanceRecorder) Fresh() TypeVariable {
+	tv := r.Fresher.Fresh()
+	r.tvs = append(r.tvs, tv)
+	return tv
+}
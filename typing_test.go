@@ -0,0 +1,98 @@
+package hm
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestInferTyped(t *testing.T) {
+	a, b := TypeVariable('a'), TypeVariable('b')
+	env := SimpleEnv{
+		"pair": NewScheme(TypeVarSet{a, b}, NewFnType(a, b, NewRecordType("", a, b))),
+	}
+
+	// let id = λx. x in pair (id 1) true
+	idλ := λ{"x", lit("x")}
+	idOne := app{lit("id"), lit("1")}
+	expr := let{"id", idλ, app{app{lit("pair"), idOne}, lit("true")}}
+
+	for _, opts := range [][]InferOpt{nil, {WithUnionFind()}} {
+		typing, err := InferTyped(env, expr, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprintf("%v", typing.Scheme); got != "∀[]: (Float, Bool)" {
+			t.Errorf("Expected the scheme to be ∀[]: (Float, Bool). Got %v", got)
+		}
+
+		// every node is typed, children before parents
+		if len(typing.Nodes) != 10 {
+			t.Errorf("Expected 10 typed nodes. Got %d", len(typing.Nodes))
+		}
+		if last := typing.Nodes[len(typing.Nodes)-1]; !sameExpr(last.Expr, expr) || fmt.Sprintf("%v", last.Type) != "(Float, Bool)" {
+			t.Errorf("Expected the whole expression to be the last node. Got %v: %v", last.Expr, last.Type)
+		}
+
+		typeOfTests := []struct {
+			expr    Expression
+			correct string
+		}{
+			{idOne, "Float"},
+			{lit("1"), "Float"},
+			{lit("true"), "Bool"},
+			{app{lit("pair"), idOne}, "Bool → (Float, Bool)"},
+		}
+		for _, tt := range typeOfTests {
+			got, ok := typing.TypeOf(tt.expr)
+			if !ok {
+				t.Errorf("Expected %v to be typed", tt.expr)
+				continue
+			}
+			if fmt.Sprintf("%v", got) != tt.correct {
+				t.Errorf("Expected %v to be %v. Got %v", tt.expr, tt.correct, got)
+			}
+		}
+
+		// the type of the definition is the type before it's generalized
+		if got, ok := typing.TypeOf(idλ); !ok {
+			t.Errorf("Expected λx. x to be typed")
+		} else if ft, ok := got.(*FunctionType); !ok || !ft.Arg().Eq(ft.Ret(false)) {
+			t.Errorf("Expected λx. x to be a → a. Got %v", got)
+		}
+
+		sc, ok := typing.SchemeOf(expr)
+		if !ok || len(typing.Bindings) != 1 || typing.Bindings[0].Name != "id" {
+			t.Fatalf("Expected id to be bound. Got %v", typing.Bindings)
+		}
+		if sc.Normalize(); fmt.Sprintf("%v", sc) != "∀[a]: a → a" {
+			t.Errorf("Expected id to be ∀[a]: a → a. Got %v", sc)
+		}
+
+		instantiations := make(map[string]string)
+		for _, inst := range typing.Instantiations {
+			instantiations[inst.Name] = fmt.Sprintf("%v", inst.Types)
+		}
+		if instantiations["id"] != "[Float]" {
+			t.Errorf("Expected id to be instantiated with [Float]. Got %v", instantiations["id"])
+		}
+		if instantiations["pair"] != "[Float Bool]" {
+			t.Errorf("Expected pair to be instantiated with [Float Bool]. Got %v", instantiations["pair"])
+		}
+	}
+
+	// a tuple is not comparable, so it can't be found, but it's still typed
+	typing, err := InferTyped(env, tuple{lit("1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := typing.TypeOf(tuple{lit("1")}); ok {
+		t.Errorf("Expected a tuple to not be found")
+	}
+	if len(typing.Nodes) != 2 || fmt.Sprintf("%v", typing.Nodes[1].Type) != "(Float)" {
+		t.Errorf("Expected the tuple to be typed. Got %v", typing.Nodes)
+	}
+
+	if _, err := InferTyped(env, lit("undefined")); err == nil {
+		t.Errorf("Expected an error")
+	}
+}
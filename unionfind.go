@@ -0,0 +1,479 @@
+package hm
+
+import "reflect"
+
+// ufSolver is an alternative to solver.
+//
+// Instead of building a substitution for every pair of types and composing it with all the substitutions that came before,
+// each TypeVariable is a cell in a union-find forest (with union by rank and path compression).
+// An equivalence class of TypeVariables may be bound to a non-variable Type, which is stored in the root of the class.
+// The substitution is only built at the end, which is also when the occurs check happens.
+//
+// RecursiveTypes are rolled up into TypeVariables whose classes are bound to the bodies of the RecursiveTypes, so they are cycles in the forest.
+// Two classes that are both bound are merged before their types are unified, so unifying cyclic types terminates,
+// and a cycle is turned back into a RecursiveType when the substitution is built.
+//
+// Solving is near-linear in the number of constraints, whereas solver is at least quadratic.
+type ufSolver struct {
+	index  map[TypeVariable]int // the cell of each TypeVariable
+	tvs    []TypeVariable       // the TypeVariable of each cell
+	parent []int
+	rank   []int
+	bound  []Type   // the Type the class is bound to. Only meaningful at the root of a class
+	rigid  []Skolem // the Skolems of the ForallTypes that have been unified. They may not escape into the substitution
+	cyclic []bool   // whether a RecursiveType has been rolled up into the class, so the class may be cyclic. Only meaningful at the root of a class
+
+	rolled    map[*RecursiveType]TypeVariable // the TypeVariable that each RecursiveType has been rolled up into
+	assumed   map[assumption]bool             // the bound classes that are assumed to unify with types, when there may be cycles
+	recursive bool                            // any class may be cyclic
+	fresher   Fresher                         // generates the TypeVariables of the tails of rows and of the rolled up RecursiveTypes
+
+	sub Subs
+	err error
+}
+
+func newUFSolver() *ufSolver {
+	return &ufSolver{index: make(map[TypeVariable]int)}
+}
+
+// cell returns the cell of the TypeVariable, creating a new one if necessary
+func (s *ufSolver) cell(tv TypeVariable) int {
+	if i, ok := s.index[tv]; ok {
+		return i
+	}
+	i := len(s.tvs)
+	s.index[tv] = i
+	s.tvs = append(s.tvs, tv)
+	s.parent = append(s.parent, i)
+	s.rank = append(s.rank, 0)
+	s.bound = append(s.bound, nil)
+	s.cyclic = append(s.cyclic, false)
+	return i
+}
+
+// find returns the root of the class of cell i, compressing the path along the way
+func (s *ufSolver) find(i int) int {
+	for s.parent[i] != i {
+		s.parent[i] = s.parent[s.parent[i]]
+		i = s.parent[i]
+	}
+	return i
+}
+
+// resolve returns the type that t stands for: if t is a TypeVariable,
+// it's either the Type its class is bound to, or the TypeVariable representing the class.
+func (s *ufSolver) resolve(t Type) Type {
+	tv, ok := t.(TypeVariable)
+	if !ok {
+		return t
+	}
+	r := s.find(s.cell(tv))
+	if s.bound[r] != nil {
+		return s.bound[r]
+	}
+	return s.tvs[r]
+}
+
+// union merges the classes of two TypeVariables. The merged class keeps the bound type of the class of b, if any.
+func (s *ufSolver) union(a, b TypeVariable) {
+	ra, rb := s.find(s.cell(a)), s.find(s.cell(b))
+	if ra == rb {
+		return
+	}
+	bound, cyclic := s.bound[rb], s.cyclic[ra] || s.cyclic[rb]
+	if bound == nil {
+		bound = s.bound[ra]
+	}
+
+	r := rb
+	switch {
+	case s.rank[ra] < s.rank[rb]:
+		s.parent[ra] = rb
+	case s.rank[ra] > s.rank[rb]:
+		s.parent[rb] = ra
+		r = ra
+	default:
+		s.parent[ra] = rb
+		s.rank[rb]++
+	}
+	s.bound[r], s.cyclic[r] = bound, cyclic
+}
+
+// merge merges the classes of two TypeVariables that are both bound, and returns the types they were bound to, which still have to be unified.
+// The classes are merged first, so that unifying the types of cyclic classes terminates.
+// It returns false if the types aren't TypeVariables that can be merged.
+func (s *ufSolver) merge(x, y Type) (a, b Type, ok bool) {
+	xtv, xIsVar := x.(TypeVariable)
+	ytv, yIsVar := y.(TypeVariable)
+	if !xIsVar || !yIsVar {
+		return nil, nil, false
+	}
+	rx, ry := s.find(s.cell(xtv)), s.find(s.cell(ytv))
+	if s.bound[rx] == nil || s.bound[ry] == nil {
+		// an unbound class joins a cyclic class, so that its TypeVariable is the RecursiveType itself, rather than its unfolding
+		if (s.bound[rx] != nil && s.cyclic[rx]) || (s.bound[ry] != nil && s.cyclic[ry]) {
+			s.union(xtv, ytv)
+			return nil, nil, true
+		}
+		return nil, nil, false
+	}
+	if rx == ry {
+		return nil, nil, true
+	}
+	a, b = s.bound[rx], s.bound[ry]
+	s.union(xtv, ytv)
+	return a, b, true
+}
+
+// assumption is the assumption that the class with the root unifies with the type
+type assumption struct {
+	root int
+	t    Type
+}
+
+// assume returns true if the class of x has already been assumed to unify with y. Otherwise the assumption is made.
+// The assumptions are only made if there may be cycles, and only for the types that are pointers, which are shared by the bound types and unfolded the same way every time.
+// Without them, unifying a cyclic class with the type it's bound to would never end.
+func (s *ufSolver) assume(x, y Type) bool {
+	if !s.recursive && s.rolled == nil {
+		return false
+	}
+	tv, ok := x.(TypeVariable)
+	if !ok || reflect.ValueOf(y).Kind() != reflect.Ptr {
+		return false
+	}
+	r := s.find(s.cell(tv))
+	if s.bound[r] == nil {
+		return false
+	}
+	a := assumption{r, y}
+	if s.assumed[a] {
+		return true
+	}
+	if s.assumed == nil {
+		s.assumed = make(map[assumption]bool)
+	}
+	s.assumed[a] = true
+	return false
+}
+
+// roll rolls a RecursiveType up into a TypeVariable whose class is bound to the body of the RecursiveType,
+// with the bound TypeVariable of the RecursiveType replaced by the class's TypeVariable.
+func (s *ufSolver) roll(t *RecursiveType) TypeVariable {
+	if tv, ok := s.rolled[t]; ok {
+		return tv
+	}
+	if s.rolled == nil {
+		s.rolled = make(map[*RecursiveType]TypeVariable)
+	}
+	tv := s.fresher.Fresh()
+	s.rolled[t] = tv
+	i := s.cell(tv)
+	s.bound[i] = cloneType(t.t).Apply(mSubs{t.tv: tv}).(Type)
+	s.cyclic[i] = true
+	return tv
+}
+
+func (s *ufSolver) solve(cs Constraints) {
+	logf("solving constraints with union-find: %d", len(cs))
+	enterLoggingContext()
+	defer leaveLoggingContext()
+	if s.err != nil {
+		return
+	}
+	if s.fresher == nil {
+		s.fresher = newTypesFresher(cs.types()...)
+	}
+
+	for _, c := range cs {
+		if err := s.unify(c.a, c.b); err != nil {
+			s.err = c.fail(s.resolveType(c.a), s.resolveType(c.b), err)
+			return
+		}
+	}
+	s.finish()
+}
+
+// finish builds the substitution, and checks that none of the Skolems of the ForallTypes escape into it.
+func (s *ufSolver) finish() {
+	s.sub, s.err = s.subs()
+	if s.err != nil || len(s.rigid) == 0 {
+		return
+	}
+	for _, sub := range s.sub.Iter() {
+		if sk, ok := mentions(sub.T, s.rigid); ok {
+			s.err = SkolemEscapeError{Skolem: sk, Type: sub.T}
+			return
+		}
+	}
+}
+
+// unify unifies a and b. Unlike Unify, it uses an explicit stack instead of recursion.
+func (s *ufSolver) unify(a, b Type) error {
+	if s.fresher == nil {
+		s.fresher = newTypesFresher(a, b)
+	}
+	stack := []Type{a, b}
+	for len(stack) > 0 {
+		x, y := stack[len(stack)-2], stack[len(stack)-1]
+		stack = stack[:len(stack)-2]
+		if ta, tb, ok := s.merge(x, y); ok {
+			if ta != nil {
+				stack = append(stack, ta, tb)
+			}
+			continue
+		}
+		if s.assume(x, y) || s.assume(y, x) {
+			continue
+		}
+
+		a, b = s.resolve(x), s.resolve(y)
+		ar, aIsRecursive := a.(*RecursiveType)
+		br, bIsRecursive := b.(*RecursiveType)
+		if aIsRecursive || bIsRecursive {
+			if aIsRecursive {
+				x = s.roll(ar)
+			}
+			if bIsRecursive {
+				y = s.roll(br)
+			}
+			stack = append(stack, x, y)
+			continue
+		}
+
+		atv, aIsVar := a.(TypeVariable)
+		btv, bIsVar := b.(TypeVariable)
+		switch {
+		case aIsVar && bIsVar:
+			s.union(atv, btv)
+		case aIsVar:
+			if err := s.bind(atv, b); err != nil {
+				return err
+			}
+		case bIsVar:
+			if err := s.bind(btv, a); err != nil {
+				return err
+			}
+		default:
+			// aliases are only expanded when they have to be
+			if ea, eb, ok := expandAliases(a, b); ok {
+				stack = append(stack, ea, eb)
+				continue
+			}
+			if !a.Eq(b) {
+				if err := rigid(a, b); err != nil {
+					return err
+				}
+			}
+			af, aIsForall := a.(*ForallType)
+			bf, bIsForall := b.(*ForallType)
+			switch {
+			case aIsForall && bIsForall:
+				as, bs, sks, err := skolemizeForalls(s.fresher, af, bf)
+				if err != nil {
+					return err
+				}
+				s.rigid = append(s.rigid, sks...)
+				stack = append(stack, as, bs)
+				continue
+			case aIsForall || bIsForall:
+				return UnificationError{a, b}
+			}
+			ar, aIsRow := a.(*Row)
+			br, bIsRow := b.(*Row)
+			switch {
+			case aIsRow && bIsRow:
+				as, bs, err := unifyRows(s.fresher, s.flatten(ar), s.flatten(br))
+				if err != nil {
+					return err
+				}
+				for i := len(as) - 1; i >= 0; i-- {
+					stack = append(stack, as[i], bs[i])
+				}
+				continue
+			case aIsRow || bIsRow:
+				return UnificationError{a, b}
+			}
+
+			if !sameConstructor(a, b) {
+				return UnificationError{a, b}
+			}
+
+			atypes, btypes := componentTypes(a, b)
+			if len(atypes) == 0 && len(btypes) == 0 {
+				if !a.Eq(b) {
+					return UnificationError{a, b}
+				}
+				continue
+			}
+			if len(atypes) != len(btypes) {
+				return ArityMismatchError{append(Types(nil), atypes...), append(Types(nil), btypes...)}
+			}
+			for i := len(atypes) - 1; i >= 0; i-- {
+				stack = append(stack, atypes[i], btypes[i])
+			}
+			ReturnTypes(atypes)
+			ReturnTypes(btypes)
+		}
+	}
+	return nil
+}
+
+// bind binds the class of an unbound TypeVariable to a non-variable Type.
+//
+// The occurs check is mostly left to subs, except for the tails of rows: flatten follows the tails,
+// so a row may never end up as its own tail.
+func (s *ufSolver) bind(tv TypeVariable, t Type) error {
+	t = unaliasOccurring(tv, t)
+	if r, ok := t.(*Row); ok {
+		if f := s.flatten(r); f.tail != nil && f.tail.Eq(s.resolve(tv)) {
+			return OccursCheckError{tv, t}
+		}
+	}
+	s.bound[s.find(s.cell(tv))] = t
+	return nil
+}
+
+// flatten merges the fields of the rows that the tail of the row is bound to into the row.
+// The tail of the resulting row is either nil, or a type that is not bound to a row.
+func (s *ufSolver) flatten(r *Row) *Row {
+	for r.tail != nil {
+		t := expandAlias(s.resolve(r.tail))
+		tr, ok := t.(*Row)
+		if !ok {
+			if t != r.tail {
+				r = &Row{fields: r.fields, tail: t}
+			}
+			return r
+		}
+		r = &Row{fields: mergeFields(r.fields, tr.fields), tail: tr.tail}
+	}
+	return r
+}
+
+// subs builds the substitution from the union-find forest.
+func (s *ufSolver) subs() (Subs, error) {
+	z := newZonker(s)
+
+	sub := make(mSubs, len(s.tvs))
+	for i, tv := range s.tvs {
+		t, err := z.zonk(i)
+		if err != nil {
+			return nil, err
+		}
+		if t != tv {
+			sub[tv] = t
+		}
+	}
+	// the TypeVariables that the RecursiveTypes were rolled up into are not part of the solution
+	for _, tv := range s.rolled {
+		delete(sub, tv)
+	}
+	return sub, nil
+}
+
+// resolveType replaces the TypeVariables in t with what they are bound to, as far as it is possible. It's used for error messages.
+func (s *ufSolver) resolveType(t Type) Type {
+	z := newZonker(s)
+
+	sub := make(mSubs)
+	for _, tv := range t.FreeTypeVar() {
+		if i, ok := s.index[tv]; ok {
+			if zt, err := z.zonk(i); err == nil && zt != tv {
+				sub[tv] = zt
+			}
+		}
+	}
+	return cloneType(t).Apply(sub).(Type)
+}
+
+// zonker replaces the TypeVariables in the bound types with what they're bound to. The results are memoized per class.
+//
+// A class whose type refers back to the class itself is cyclic. If the class may be cyclic, its type is a RecursiveType that binds the class's TypeVariable.
+// Otherwise it's an OccursCheckError.
+type zonker struct {
+	*ufSolver
+	types []Type
+	state []byte // 0: unvisited, 1: visiting, 2: done
+	depth []int  // the depth of each class that is being visited
+	loops []bool // whether the type of a class that is being visited refers back to the class
+	level int    // the number of classes that are being visited
+}
+
+func newZonker(s *ufSolver) *zonker {
+	return &zonker{
+		ufSolver: s,
+		types:    make([]Type, len(s.tvs)),
+		state:    make([]byte, len(s.tvs)),
+		depth:    make([]int, len(s.tvs)),
+		loops:    make([]bool, len(s.tvs)),
+	}
+}
+
+func (z *zonker) zonk(i int) (Type, error) {
+	t, _, err := z.visit(i)
+	return t, err
+}
+
+// visit zonks the class of cell i. It also returns the lowest depth of the classes being visited that the zonked type refers back to (or -1, if there are none).
+// The type of a class that refers back to an enclosing class is only valid inside the RecursiveType of the enclosing class, so it's not memoized.
+func (z *zonker) visit(i int) (Type, int, error) {
+	r := z.find(i)
+	switch {
+	case z.bound[r] == nil:
+		return z.tvs[r], -1, nil
+	case z.state[r] == 2:
+		return z.types[r], -1, nil
+	case z.state[r] == 1:
+		if !z.recursive && !z.cyclic[r] {
+			return nil, -1, OccursCheckError{z.tvs[r], z.bound[r]}
+		}
+		z.loops[r] = true
+		return z.tvs[r], z.depth[r], nil
+	}
+	z.state[r] = 1
+	z.depth[r] = z.level
+	z.level++
+	defer func() { z.level-- }()
+
+	t := z.bound[r]
+	ftv := t.FreeTypeVar()
+	var sub mSubs
+	low := -1
+	for _, tv := range ftv {
+		j, ok := z.index[tv]
+		if !ok {
+			continue // never unified with anything
+		}
+		zt, d, err := z.visit(j)
+		if err != nil {
+			return nil, -1, err
+		}
+		if d >= 0 && (low < 0 || d < low) {
+			low = d
+		}
+		if zt != tv {
+			if sub == nil {
+				sub = make(mSubs)
+			}
+			sub[tv] = zt
+		}
+	}
+	ReturnTypeVarSet(ftv)
+
+	if sub != nil {
+		// Apply may mutate the type in place, and the bound type may be shared by the constraints
+		t = cloneType(t).Apply(sub).(Type)
+	}
+	if z.loops[r] {
+		t = NewRecursiveType(z.tvs[r], t)
+		z.loops[r] = false
+	}
+	if low >= 0 && low < z.depth[r] {
+		z.state[r] = 0
+		return t, low, nil
+	}
+	z.types[r] = t
+	z.state[r] = 2
+	return t, -1, nil
+}
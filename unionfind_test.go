@@ -0,0 +1,127 @@
+package hm
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestUFSolver(t *testing.T) {
+	for i, sts := range solverTest {
+		solver := newUFSolver()
+		solver.solve(sts.cs)
+
+		if sts.err {
+			if solver.err == nil {
+				t.Errorf("Test %d Expected an error", i)
+			}
+			continue
+		} else if solver.err != nil {
+			t.Error(solver.err)
+		}
+
+		for _, v := range sts.expected.Iter() {
+			if T, ok := solver.sub.Get(v.Tv); !ok {
+				t.Errorf("Test %d: Expected type variable %v in subs: %v", i, v.Tv, solver.sub)
+				break
+			} else if !T.Eq(v.T) {
+				t.Errorf("Test %d: Expected replacement to be %v. Got %v instead", i, v.T, T)
+			}
+		}
+	}
+}
+
+func TestUFSolver_Chain(t *testing.T) {
+	// a0 = a1 → proton, a1 = a2 → proton, ... a99 = proton
+	n := 100
+	cs := make(Constraints, n+1)
+	for i := 0; i < n; i++ {
+		cs[i] = Constraint{a: NewTypeVar(i), b: NewFnType(NewTypeVar(i+1), proton)}
+	}
+	cs[n] = Constraint{a: NewTypeVar(n), b: proton}
+
+	solver := newUFSolver()
+	solver.solve(cs)
+	if solver.err != nil {
+		t.Fatal(solver.err)
+	}
+
+	var correct Type = proton
+	for i := n; i >= 0; i-- {
+		T, ok := solver.sub.Get(NewTypeVar(i))
+		if !ok {
+			t.Fatalf("Expected %v to be in the substitution", NewTypeVar(i))
+		}
+		if !T.Eq(correct) {
+			t.Fatalf("Expected %v to be substituted with %v. Got %v instead", NewTypeVar(i), correct, T)
+		}
+		correct = NewFnType(correct, proton)
+	}
+}
+
+func TestUFSolver_Errors(t *testing.T) {
+	// a = b → proton, b = a
+	solver := newUFSolver()
+	solver.solve(Constraints{
+		{a: TypeVariable('a'), b: NewFnType(TypeVariable('b'), proton)},
+		{a: TypeVariable('b'), b: TypeVariable('a')},
+	})
+	var oe OccursCheckError
+	if !errors.As(solver.err, &oe) {
+		t.Errorf("Expected an OccursCheckError. Got %v", solver.err)
+	}
+
+	// the origin of the failed constraint is reported
+	origin := &Origin{Expr: app{lit("f"), lit("x")}, Reason: ReasonApply}
+	solver = newUFSolver()
+	solver.solve(Constraints{
+		{a: TypeVariable('a'), b: proton},
+		{a: NewFnType(TypeVariable('a'), TypeVariable('a')), b: NewFnType(TypeVariable('b'), neutron), origin: origin},
+	})
+	var ce *ConstraintError
+	if !errors.As(solver.err, &ce) {
+		t.Fatalf("Expected a *ConstraintError. Got %v", solver.err)
+	}
+	if ce.Origin != origin {
+		t.Errorf("Expected the origin of the second constraint. Got %v", ce.Origin)
+	}
+	var ue UnificationError
+	if !errors.As(solver.err, &ue) || ue.Left != proton || ue.Right != neutron {
+		t.Errorf("Expected proton ~ neutron to have failed. Got %v", solver.err)
+	}
+}
+
+func TestInfer_WithUnionFind(t *testing.T) {
+	env := SimpleEnv{
+		"+":  &Scheme{tvs: TypeVarSet{'a'}, t: NewFnType(TypeVariable('a'), TypeVariable('a'), TypeVariable('a'))},
+		"+1": &Scheme{tvs: TypeVarSet{'a'}, t: NewFnType(TypeVariable('a'), TypeVariable('a'))},
+		"x":  NewScheme(nil, proton),
+	}
+
+	for _, its := range inferTests {
+		expected, err1 := Infer(env.Clone(), its.expr)
+		sch, err2 := Infer(env.Clone(), its.expr, WithUnionFind())
+
+		if (err1 == nil) != (err2 == nil) {
+			t.Errorf("Test %q: Expected both engines to agree on errors. Got %v and %v", its.name, err1, err2)
+			continue
+		}
+		if err1 != nil {
+			continue
+		}
+
+		if !sch.t.Eq(expected.t) || !sch.tvs.Equals(expected.tvs) {
+			t.Errorf("Test %q: Expected %v. Got %v", its.name, expected, sch)
+		}
+	}
+
+	// let polymorphism
+	expr := let{"id", λ{"x", lit("x")}, app{app{lit("id"), lit("id")}, lit("1")}}
+	sch, err := Infer(nil, expr, WithUnionFind())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sch.t.Eq(Float) {
+		t.Errorf("Expected Float. Got %v", sch)
+	}
+}